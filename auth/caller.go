@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+)
+
+// 调用方身份
+// 写入 create_by / update_by 审计列的操作者从 ctx 中的 Caller 取得：
+// RPC 请求由 UnaryServerInterceptor 放入，消息消费者、定时任务等后台流程用 System 标记，
+// 都没有时记为 system。
+// 开启鉴权（auth.enable）时 Caller 来自校验通过的 JWT，否则来自请求中的 user_id 和 x-operator，只用于审计。
+
+// _systemActor ctx 中没有调用方信息时记录的操作者
+const _systemActor = "system"
+
+// Caller 调用方
+type Caller struct {
+	UserID   int64  // 用户ID，运维人员和后台任务为 0
+	Name     string // 运维人员或后台任务的名称
+	Admin    bool   // 是否为运维人员
+	Verified bool   // 身份是否经过 JWT 校验
+}
+
+// String 写入审计列的操作者，用户为 user:{user_id}，运维人员为 operator:{name}
+func (c Caller) String() string {
+	if c.Admin && len(c.Name) > 0 {
+		return c.Name
+	}
+	if c.UserID > 0 {
+		return "user:" + strconv.FormatInt(c.UserID, 10)
+	}
+	if len(c.Name) > 0 {
+		return c.Name
+	}
+	return _systemActor
+}
+
+// System 后台任务，name 为任务名称，例如 timeout_scanner
+func System(name string) Caller {
+	return Caller{Name: _systemActor + ":" + name}
+}
+
+type callerKey struct{}
+
+// NewContext 把调用方放入 ctx
+func NewContext(ctx context.Context, c Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, c)
+}
+
+// FromContext 从 ctx 中取调用方
+func FromContext(ctx context.Context) (Caller, bool) {
+	c, ok := ctx.Value(callerKey{}).(Caller)
+	return c, ok
+}
+
+// CanAccess 调用方是否可以访问 userId 的订单：用户只能访问自己的订单，运维人员可以访问所有订单
+// 没有开启鉴权（调用方未经校验）或者是后台任务时不限制
+func CanAccess(ctx context.Context, userId int64) bool {
+	c, ok := FromContext(ctx)
+	if !ok || !c.Verified || c.Admin {
+		return true
+	}
+	return c.UserID == userId
+}
+
+// IsAdmin 调用方是否为经过 JWT 校验的运维人员（包括使用运维身份调用的支付服务等内部服务）
+func IsAdmin(ctx context.Context) bool {
+	c, ok := FromContext(ctx)
+	return ok && c.Verified && c.Admin
+}
+
+// Actor ctx 对应的操作者，用于填充 create_by / update_by
+func Actor(ctx context.Context) string {
+	if ctx == nil {
+		return _systemActor
+	}
+	c, _ := FromContext(ctx)
+	return c.String()
+}
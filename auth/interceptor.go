@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"order_service/logger"
+	"order_service/proto"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	protov2 "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// OperatorKey 没有开启鉴权时，运维后台在 gRPC metadata 中传入的操作人，经过网关时使用 HTTP 头 Grpc-Metadata-X-Operator
+	OperatorKey = "x-operator"
+	// AuthorizationKey JWT 在 gRPC metadata 中的 key，经过网关时使用 HTTP 头 Authorization
+	AuthorizationKey = "authorization"
+
+	_bearerPrefix = "Bearer "
+	_userIDField  = "user_id"
+)
+
+// userIDGetter proto 生成的请求结构体有 user_id 字段时实现该接口
+type userIDGetter interface {
+	GetUserId() int64
+}
+
+// UnaryServerInterceptor 识别请求的调用方并放入 ctx
+//
+// 没有开启鉴权时：metadata 中有 x-operator 时为运维人员，否则为请求中的 user_id，只用于审计。
+// 开启鉴权时：
+//   - 健康检查不需要 token，其他接口 token 无效时返回 Unauthenticated
+//   - Admin 接口只有运维人员可以调用，否则返回 PermissionDenied
+//   - 普通用户请求中的 user_id 必须是自己，为 0 时填入当前用户；只有订单号的请求由 biz 层检查订单归属
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		v := _verifier.Load()
+		if v == nil {
+			if c, ok := callerFromRequest(ctx, req); ok {
+				ctx = NewContext(ctx, c)
+			}
+			return handler(ctx, req)
+		}
+		if serviceName(info.FullMethod) == grpc_health_v1.Health_ServiceDesc.ServiceName {
+			return handler(ctx, req)
+		}
+
+		c, err := v.verify(tokenFromContext(ctx))
+		if err != nil {
+			logger.Ctx(ctx).Info("authentication failed", zap.Error(err))
+			return nil, status.Error(codes.Unauthenticated, "未登录或登录已过期")
+		}
+		if err := authorize(c, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(NewContext(ctx, c), req)
+	}
+}
+
+// callerFromRequest 没有开启鉴权时从请求中识别调用方
+func callerFromRequest(ctx context.Context, req interface{}) (Caller, bool) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ops := md.Get(OperatorKey); len(ops) > 0 && ops[0] != "" {
+			return Caller{Name: "operator:" + ops[0]}, true
+		}
+	}
+	if r, ok := req.(userIDGetter); ok && r.GetUserId() > 0 {
+		return Caller{UserID: r.GetUserId()}, true
+	}
+	return Caller{}, false
+}
+
+// tokenFromContext 从 metadata 中取 Bearer token，没有时返回空字符串
+func tokenFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(AuthorizationKey)
+	if len(values) == 0 || len(values[0]) < len(_bearerPrefix) || !strings.EqualFold(values[0][:len(_bearerPrefix)], _bearerPrefix) {
+		return ""
+	}
+	return strings.TrimSpace(values[0][len(_bearerPrefix):])
+}
+
+// authorize 检查调用方是否可以调用 method
+func authorize(c Caller, method string, req interface{}) error {
+	if serviceName(method) == proto.Admin_ServiceDesc.ServiceName {
+		if !c.Admin {
+			return status.Error(codes.PermissionDenied, "没有权限")
+		}
+		return nil
+	}
+	if c.Admin {
+		return nil
+	}
+	if err := bindUser(c, req); err != nil {
+		return status.Error(codes.PermissionDenied, "不能访问其他用户的订单")
+	}
+	return nil
+}
+
+// bindUser 请求中的 user_id 为 0 时填入当前用户，不是当前用户时返回错误
+func bindUser(c Caller, req interface{}) error {
+	m, ok := req.(protov2.Message)
+	if !ok {
+		return nil
+	}
+	msg := m.ProtoReflect()
+	fd := msg.Descriptor().Fields().ByName(_userIDField)
+	if fd == nil || fd.Kind() != protoreflect.Int64Kind {
+		return nil
+	}
+	switch msg.Get(fd).Int() {
+	case c.UserID:
+	case 0:
+		msg.Set(fd, protoreflect.ValueOfInt64(c.UserID))
+	default:
+		return errors.New("user_id mismatch")
+	}
+	return nil
+}
+
+// serviceName 从 /package.Service/Method 中取出服务名
+func serviceName(fullMethod string) string {
+	name := strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
@@ -0,0 +1,133 @@
+package coupon
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"order_service/biz/pricing"
+	"order_service/dao/redis"
+	"order_service/dao/store"
+	"order_service/errno"
+	"order_service/logger"
+	"order_service/metrics"
+	"order_service/model"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 优惠券和订单生命周期的绑定
+// 创建订单时锁定（Reserve），支付后核销（Confirm），取消或超时释放（Release）。
+// 每张券只能被一个订单使用：redis SETNX 挡住并发的重复使用，数据库条件更新保存最终状态。
+// 配置文件中的通用优惠券（pricing.coupons）不限次数，不需要锁定。
+
+// _claimTTLAfterExpire 券过期后 redis 中的占用记录再保留一段时间
+const _claimTTLAfterExpire = 24 * time.Hour
+
+// Init 使用数据库中发放的优惠券作为定价的优惠券来源，找不到时再查通用优惠券
+func Init() {
+	pricing.SetCouponProvider(provider{fallback: pricing.ConfigCoupons{}})
+}
+
+// provider 查询发放给用户的优惠券
+type provider struct {
+	fallback pricing.CouponProvider
+}
+
+func (p provider) GetCoupon(ctx context.Context, userId int64, code string) (*pricing.Coupon, error) {
+	c, err := store.Repo().QueryCoupon(ctx, code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return p.fallback.GetCoupon(ctx, userId, code)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.UserId != userId {
+		return nil, errno.ErrInvalidCoupon
+	}
+	if c.Status != model.CouponStatusUnused || !time.Now().Before(c.ExpireAt) {
+		return nil, errno.ErrCouponNotApplicable
+	}
+	return &pricing.Coupon{
+		Code:        c.Code,
+		Type:        c.Type,
+		Value:       c.Value,
+		MinAmount:   c.MinAmount,
+		MaxDiscount: c.MaxDiscount,
+	}, nil
+}
+
+// Reserve 为订单锁定优惠券，券已经被使用时返回 errno.ErrCouponNotApplicable
+// code 不是发放给用户的优惠券（通用优惠券）时直接返回
+func Reserve(ctx context.Context, userId int64, code string, orderId int64) error {
+	if len(code) == 0 {
+		return nil
+	}
+	c, err := store.Repo().QueryCoupon(ctx, code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(c.ExpireAt) + _claimTTLAfterExpire
+	ok, err := redis.ClaimCoupon(ctx, c.Code, orderId, ttl)
+	if err != nil {
+		metrics.CouponOperations.WithLabelValues("reserve", "error").Inc()
+		return err
+	}
+	if !ok {
+		metrics.CouponOperations.WithLabelValues("reserve", "conflict").Inc()
+		return errno.ErrCouponNotApplicable
+	}
+
+	ok, err = store.Repo().ReserveCoupon(ctx, c.Code, userId, orderId)
+	if err != nil || !ok {
+		// 数据库锁定失败时释放 redis 中的占用
+		if errRelease := redis.ReleaseCouponClaim(context.WithoutCancel(ctx), c.Code, orderId); errRelease != nil {
+			logger.Ctx(ctx).Warn("ReleaseCouponClaim failed", zap.String("code", c.Code), zap.Error(errRelease))
+		}
+	}
+	if err != nil {
+		metrics.CouponOperations.WithLabelValues("reserve", "error").Inc()
+		return err
+	}
+	if !ok {
+		metrics.CouponOperations.WithLabelValues("reserve", "conflict").Inc()
+		return errno.ErrCouponNotApplicable
+	}
+	metrics.CouponOperations.WithLabelValues("reserve", "ok").Inc()
+	logger.Ctx(ctx).Info("Coupon reserved", zap.String("code", c.Code))
+	return nil
+}
+
+// Confirm 订单支付后核销锁定的优惠券，可重复调用
+func Confirm(ctx context.Context, orderId int64) error {
+	if err := store.Repo().ConfirmCoupon(ctx, orderId); err != nil {
+		metrics.CouponOperations.WithLabelValues("confirm", "error").Inc()
+		return err
+	}
+	metrics.CouponOperations.WithLabelValues("confirm", "ok").Inc()
+	return nil
+}
+
+// Release 订单取消或超时后释放锁定的优惠券，可重复调用
+func Release(ctx context.Context, orderId int64) error {
+	code, err := store.Repo().ReleaseCoupon(ctx, orderId)
+	if err != nil {
+		metrics.CouponOperations.WithLabelValues("release", "error").Inc()
+		return err
+	}
+	if len(code) == 0 {
+		return nil
+	}
+	if err := redis.ReleaseCouponClaim(ctx, code, orderId); err != nil {
+		// 数据库已经释放，redis 中的占用没有删除时券暂时无法使用，到期后自动删除
+		logger.Ctx(ctx).Warn("ReleaseCouponClaim failed", zap.String("code", code), zap.Error(err))
+	}
+	metrics.CouponOperations.WithLabelValues("release", "ok").Inc()
+	logger.Ctx(ctx).Info("Coupon released", zap.String("code", code))
+	return nil
+}
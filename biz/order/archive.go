@@ -0,0 +1,117 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"order_service/config"
+	"order_service/dao/redis"
+	"order_service/dao/store"
+	"order_service/errno"
+	"order_service/logger"
+	"order_service/metrics"
+	"order_service/tracing"
+
+	"go.uber.org/zap"
+)
+
+// 冷订单归档
+// 定期把已完成、已取消、支付超时并且创建超过 archive.min_age 的订单移动到归档表（见 orm/archive.go），
+// 多个实例通过任务锁保证同一时间只有一个在归档；每批之间检查暂停标记，暂停后当前批次结束就停止。
+
+const _archiveJob = "archive"
+
+// StartArchiver 启动冷订单归档任务，archive.interval 为 0 时不启动
+func StartArchiver(ctx context.Context) {
+	cfg := config.Get().ArchiveConfig
+	if cfg == nil || cfg.Interval <= 0 {
+		return
+	}
+	logger.Ctx(ctx).Info("Starting order archiver", zap.Duration("interval", cfg.Interval))
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Ctx(ctx).Info("Order archiver stopped")
+			return
+		case <-ticker.C:
+			_, err := ArchiveOrders(ctx)
+			if err != nil && !errors.Is(err, errno.ErrJobRunning) {
+				logger.Ctx(ctx).Error("ArchiveOrders failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// ArchiveOrders 执行一次归档，逐个库分批归档，直到没有可以归档的订单、任务被暂停或者 ctx 取消
+// 返回归档的订单数，其他实例正在归档时返回 errno.ErrJobRunning
+func ArchiveOrders(ctx context.Context) (int, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "archiveOrders")
+	defer span.End()
+	ctx = logger.NewContext(ctx, logger.TraceField(ctx))
+
+	var total int
+	err := redis.WithJobLock(ctx, _archiveJob, func(ctx context.Context) error {
+		cfg := config.Get().ArchiveConfig
+		before := time.Now().Add(-cfg.MinAge)
+		for _, db := range store.Shards() {
+			for {
+				paused, err := redis.ArchivePaused(ctx)
+				if err != nil {
+					return err
+				}
+				if paused {
+					logger.Ctx(ctx).Info("Order archiver paused", zap.Int("archived", total))
+					return nil
+				}
+
+				n, err := db.ArchiveOrders(ctx, before, cfg.BatchSize)
+				total += n
+				metrics.OrdersArchived.WithLabelValues(db.Name()).Add(float64(n))
+				if err != nil {
+					return err
+				}
+				if n < cfg.BatchSize {
+					break
+				}
+
+				select {
+				case <-ctx.Done():
+					return context.Cause(ctx)
+				case <-time.After(cfg.BatchInterval):
+				}
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errno.ErrJobRunning) {
+		return 0, err
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	if err := redis.SaveArchiveRun(context.WithoutCancel(ctx), time.Now(), total, err); err != nil {
+		logger.Ctx(ctx).Warn("save archive state failed", zap.Error(err))
+	}
+	logger.Ctx(ctx).Info("Orders archived", zap.Int("archived", total), zap.Error(err))
+	return total, err
+}
+
+// SetArchivePaused 暂停或恢复归档任务，对所有实例生效
+// 暂停时正在执行的归档在当前批次结束后停止
+func SetArchivePaused(ctx context.Context, paused bool) error {
+	if err := redis.SetArchivePaused(ctx, paused); err != nil {
+		return err
+	}
+	logger.Ctx(ctx).Info("Order archiver paused state changed", zap.Bool("paused", paused))
+	return nil
+}
+
+// ArchiveState 查询归档任务的状态
+func ArchiveState(ctx context.Context) (redis.ArchiveState, error) {
+	return redis.GetArchiveState(ctx)
+}
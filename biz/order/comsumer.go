@@ -3,13 +3,13 @@ package order
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"order_service/config"
 	"order_service/dao/mq"
-	"order_service/dao/mysql"
+	"order_service/logger"
+	"order_service/metrics"
 	"order_service/model"
-	"order_service/proto"
-	"order_service/rpc"
+	"order_service/tracing"
+	"time"
 
 	"github.com/apache/rocketmq-client-go/v2/consumer"
 	"github.com/apache/rocketmq-client-go/v2/primitive"
@@ -18,76 +18,85 @@ import (
 
 // OrderTimeouthandle 是处理订单超时消息的回调函数
 // 消费者处理订单超时消息
-// 添加消费者消息重试机制，超过重试次数则会存入死信队列，后续进行人工处理。
+// 添加消费者消息重试机制，超过重试次数（rocketmq.max_reconsume_times）则转入死信 topic，后续进行人工处理。
 func OrderTimeouthandle(ctx context.Context, msgs ...*primitive.MessageExt) (consumer.ConsumeResult, error) {
 	for _, msg := range msgs {
 		// 检查消息主题是否是订单超时主题
-		if msg.Topic != config.Conf.RocketMqConfig.Topic.PayTimeOut {
-			zap.L().Info("Message topic does not match order timeout topic, skipping", zap.String("topic", msg.Topic))
+		if msg.Topic != config.Get().RocketMqConfig.Topic.PayTimeOut {
+			logger.Ctx(ctx).Info("Message topic does not match order timeout topic, skipping", zap.String("topic", msg.Topic))
 			continue
 		}
 
-		// 解析消息内容
-		var orderDetail model.OrderDetail
-		err := json.Unmarshal(msg.Body, &orderDetail)
-		if err != nil {
-			zap.L().Error("Failed to unmarshal order detail", zap.Error(err))
-			return consumer.ConsumeRetryLater, err
+		// 消费延迟：消息写入（延迟消息为到期投递）到被消费的时间差
+		if msg.StoreTimestamp > 0 {
+			lag := time.Since(time.UnixMilli(msg.StoreTimestamp))
+			metrics.ConsumeLag.WithLabelValues(msg.Topic).Observe(lag.Seconds())
+		}
+		if msg.ReconsumeTimes > 0 {
+			metrics.ConsumeRetries.WithLabelValues(msg.Topic).Inc()
 		}
 
-		// 判断订单是否超时
-		switch orderDetail.Status {
-		case "unpaid":
-			// 如果订单状态为“未支付”，执行超时处理逻辑
-			zap.L().Info("Order is unpaid, processing timeout", zap.Int64("OrderId", orderDetail.OrderId))
-
-			// 1. 回滚库存
-			_, err = rpc.StockCli.RollbackStock(context.Background(), &proto.ReduceStockInfo{
-				GoodsId: orderDetail.GoodsId,
-				Num:     orderDetail.Num,
-				OrderId: orderDetail.OrderId,
-			})
-			if err != nil {
-				zap.L().Error("Failed to rollback stock", zap.Error(err), zap.Int64("OrderId", orderDetail.OrderId))
-				return consumer.ConsumeRetryLater, err
-			}
-
-			// 2. 更新订单状态为“已超时”
-			orderDetail.Status = "timeout"
-			err = mysql.UpdateOrderStatus(context.Background(), &orderDetail)
-			if err != nil {
-				zap.L().Error("Failed to update order status to timeout", zap.Error(err), zap.Int64("OrderId", orderDetail.OrderId))
-				return consumer.ConsumeRetryLater, err
+		// 从消息属性中恢复创建订单时的 trace 上下文
+		msgCtx, span := tracing.StartConsumerSpan(ctx, msg)
+		msgCtx = logger.NewContext(msgCtx,
+			zap.String("msg_id", msg.MsgId),
+			zap.String("topic", msg.Topic),
+			logger.TraceField(msgCtx),
+		)
+		result, err := handleTimeoutMessage(msgCtx, msg)
+		if err != nil {
+			span.RecordError(err)
+		}
+		if result != consumer.ConsumeSuccess && msg.ReconsumeTimes >= config.Get().RocketMqConfig.MaxReconsumeTimes {
+			// 已经达到最大重试次数，转入死信 topic，不再重试
+			if dlqErr := sendDeadLetter(msgCtx, msg); dlqErr == nil {
+				result, err = consumer.ConsumeSuccess, nil
 			}
-
-			// 3. 发送超时通知（可选）
-			// utils.SendOrderTimeoutNotification(orderDetail.OrderId)
-		case "paid", "cancelled", "timeout":
-			// 如果订单状态不是“未支付”，记录日志并忽略
-			zap.L().Info("Order already processed, ignoring timeout message",
-				zap.Int64("OrderId", orderDetail.OrderId),
-				zap.String("status", orderDetail.Status))
-		default:
-			zap.L().Error("Unknown order status", zap.String("status", orderDetail.Status))
-			return consumer.ConsumeRetryLater, fmt.Errorf("unknown order status: %s", orderDetail.Status)
 		}
+		span.End()
+		if result != consumer.ConsumeSuccess {
+			metrics.ConsumeResults.WithLabelValues(msg.Topic, "retry").Inc()
+			return result, err
+		}
+	}
 
-		// 检查重试次数
-		reconsumeTimes := msg.ReconsumeTimes
-		maxReconsumeTimes := int32(3) // 最大重试次数
+	return consumer.ConsumeSuccess, nil
+}
 
-		if reconsumeTimes >= maxReconsumeTimes {
-			// 发送到死信队列
-			deadLetterMsg := primitive.NewMessage("dead_letter_queue", msg.Body)
-			_, err := mq.Producer.SendSync(context.Background(), deadLetterMsg)
-			if err != nil {
-				zap.L().Error("Failed to send message to dead letter queue", zap.Error(err))
-				return consumer.ConsumeRetryLater, err
-			}
-			zap.L().Info("Message moved to dead letter queue", zap.Int64("OrderId", orderDetail.OrderId))
-			return consumer.ConsumeSuccess, nil
-		}
+// handleTimeoutMessage 处理单条订单超时消息
+func handleTimeoutMessage(ctx context.Context, msg *primitive.MessageExt) (consumer.ConsumeResult, error) {
+	// 解析消息内容
+	var orderDetail model.OrderDetail
+	err := json.Unmarshal(msg.Body, &orderDetail)
+	if err != nil {
+		logger.Ctx(ctx).Error("Failed to unmarshal order detail", zap.Error(err))
+		return consumer.ConsumeRetryLater, err
 	}
+	ctx = logger.NewContext(ctx, zap.Int64("order_id", orderDetail.OrderId))
 
+	// 消息里的订单状态是发送时的状态，是否超时以数据库中的最新状态为准
+	if err = closeTimeoutOrder(ctx, orderDetail.OrderId, "consumer"); err != nil {
+		logger.Ctx(ctx).Error("Failed to close timeout order", zap.Error(err))
+		return consumer.ConsumeRetryLater, err
+	}
+
+	metrics.ConsumeResults.WithLabelValues(msg.Topic, "success").Inc()
 	return consumer.ConsumeSuccess, nil
 }
+
+// sendDeadLetter 把消费失败的消息转发到死信 topic
+// 保留原 topic 和消息 ID，方便人工处理时追溯
+func sendDeadLetter(ctx context.Context, msg *primitive.MessageExt) error {
+	deadLetterMsg := primitive.NewMessage(config.Get().RocketMqConfig.Topic.DeadLetter, msg.Body)
+	deadLetterMsg.WithProperty("ORIGIN_TOPIC", msg.Topic)
+	deadLetterMsg.WithProperty("ORIGIN_MSG_ID", msg.MsgId)
+	tracing.InjectMessage(ctx, deadLetterMsg)
+	_, err := mq.Producer.SendSync(ctx, deadLetterMsg)
+	if err != nil {
+		logger.Ctx(ctx).Error("Failed to send message to dead letter queue", zap.Error(err))
+		return err
+	}
+	logger.Ctx(ctx).Warn("Message moved to dead letter queue", zap.Int32("reconsume_times", msg.ReconsumeTimes))
+	metrics.ConsumeResults.WithLabelValues(msg.Topic, "dead_letter").Inc()
+	return nil
+}
@@ -0,0 +1,32 @@
+package order
+
+import (
+	"context"
+
+	"order_service/auth"
+	"order_service/dao/cache"
+	"order_service/logger"
+
+	"go.uber.org/zap"
+)
+
+// 订单软删除
+// 运维后台删除已经结束的订单，删除后用户查不到该订单，误删时可以恢复，操作人记录在 update_by 中。
+
+// Delete 软删除订单，订单还没有结束时返回 errno.ErrOrderNotDeletable
+func Delete(ctx context.Context, orderId int64) error {
+	if err := cache.DeleteOrder(ctx, orderId); err != nil {
+		return err
+	}
+	logger.Ctx(ctx).Info("order deleted", zap.String("operator", auth.Actor(ctx)))
+	return nil
+}
+
+// Restore 恢复已经软删除的订单
+func Restore(ctx context.Context, orderId int64) error {
+	if err := cache.RestoreOrder(ctx, orderId); err != nil {
+		return err
+	}
+	logger.Ctx(ctx).Info("order restored", zap.String("operator", auth.Actor(ctx)))
+	return nil
+}
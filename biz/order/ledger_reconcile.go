@@ -0,0 +1,74 @@
+package order
+
+import (
+	"context"
+	"time"
+
+	"order_service/config"
+	"order_service/dao/store"
+	"order_service/logger"
+	"order_service/metrics"
+	"order_service/model"
+	"order_service/tracing"
+
+	"go.uber.org/zap"
+)
+
+// StartLedgerReconciler 启动库存台账对账任务，定期对比台账和订单状态，报告不一致的记录
+// ledger.reconcile_interval 为 0 时不启动
+func StartLedgerReconciler(ctx context.Context) {
+	interval := config.Get().LedgerConfig.ReconcileInterval
+	if interval <= 0 {
+		return
+	}
+	logger.Ctx(ctx).Info("Starting stock ledger reconciler", zap.Duration("interval", interval))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Ctx(ctx).Info("Stock ledger reconciler stopped")
+			return
+		case <-ticker.C:
+			ReconcileLedger(ctx)
+		}
+	}
+}
+
+// ReconcileLedger 执行一次库存台账对账
+func ReconcileLedger(ctx context.Context) []model.LedgerDrift {
+	ctx, span := tracing.Tracer().Start(ctx, "reconcileStockLedger")
+	defer span.End()
+	ctx = logger.NewContext(ctx, logger.TraceField(ctx))
+
+	cfg := config.Get().LedgerConfig
+	drifts, err := store.Repo().QueryLedgerDrifts(ctx, time.Now().Add(-cfg.Window), cfg.Grace)
+	if err != nil {
+		logger.Ctx(ctx).Error("QueryLedgerDrifts failed", zap.Error(err))
+		return nil
+	}
+
+	counts := map[string]int{
+		model.DriftMissingRollback:    0,
+		model.DriftUnexpectedRollback: 0,
+		model.DriftStuckRollback:      0,
+		model.DriftOrphanDeduction:    0,
+		model.DriftMissingLedger:      0,
+	}
+	for _, d := range drifts {
+		counts[d.Kind]++
+		logger.Ctx(ctx).Warn("Stock ledger drift",
+			zap.String("kind", d.Kind),
+			zap.Int64("order_id", d.OrderId),
+			zap.Int64("goods_id", d.GoodsId),
+			zap.String("ledger_status", d.LedgerStatus),
+			zap.String("order_status", d.OrderStatus),
+		)
+	}
+	for kind, n := range counts {
+		metrics.StockLedgerDrift.WithLabelValues(kind).Set(float64(n))
+	}
+	logger.Ctx(ctx).Info("Stock ledger reconciled", zap.Int("drifts", len(drifts)))
+	return drifts
+}
@@ -3,20 +3,28 @@ package order
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"sync"
+	"time"
 
+	"order_service/auth"
+	"order_service/biz/coupon"
 	"order_service/config"
 	"order_service/dao/mq"
-	"order_service/dao/mysql"             // 数据库操作模块
+	"order_service/dao/cache"
+	"order_service/dao/store"             // 数据库操作模块
+	"order_service/errno"
+	"order_service/logger"                // 日志模块
+	"order_service/metrics"               // 监控指标模块
 	"order_service/model"                 // 数据模型模块
 	"order_service/proto"                 // gRPC 服务定义模块
 	"order_service/rpc"                   // RPC 客户端初始化模块
 	"order_service/third_party/snowflake" // Snowflake ID 生成模块
+	"order_service/tracing"               // 链路追踪模块
 
-	"github.com/apache/rocketmq-client-go/v2"
 	"github.com/apache/rocketmq-client-go/v2/primitive"
-	"github.com/apache/rocketmq-client-go/v2/producer"
 	"go.uber.org/zap" // 日志库
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -34,6 +42,7 @@ type OrderEntity struct {
 	Param   *proto.CreateOrderReq // 订单请求参数
 	Topic  string                // 事务消息的主题
 	RetryCount int64			 //重试次数
+	caller  auth.Caller           // 下单的调用方，本地事务中写入订单的 create_by
 	err     error                 // 本地事务执行过程中可能产生的错误
 }
 
@@ -41,30 +50,22 @@ type OrderEntity struct {
 //订单创建的入口点，负责生成订单号并发送事务消息
 func Create(ctx context.Context, param *proto.CreateOrderReq) (*proto.Response, error) {
 
-	// 1. 生成订单号
-	orderId := snowflake.GenID()
+	// 1. 生成订单号，用户ID作为基因，分库时订单和用户落在同一个分片
+	orderId := snowflake.GenIDWithGene(param.UserId)
+	ctx = logger.NewContext(ctx, zap.Int64("order_id", orderId))
 
 	//创建OrderEntity实例，用于事务消息的上下文
+	caller, _ := auth.FromContext(ctx)
 	orderEntity := &OrderEntity{
 		OrderId: orderId,
 		Param:   param,
-		Topic:   config.Conf.RocketMqConfig.Topic.CreateOrder, // 默认Topic为创建订单
+		Topic:   config.Get().RocketMqConfig.Topic.CreateOrder, // 默认Topic为创建订单
+		caller:  caller,
 	}
 
-	//创建事务生产者
-	p, err := rocketmq.NewTransactionProducer(
-		orderEntity, // 将 OrderEntity 作为事务消息的上下文
-		producer.WithNsResolver(primitive.NewPassthroughResolver([]string{"127.0.0.1:9876"})), // 配置 RocketMQ 名称服务器地址
-		producer.WithRetry(3),                 // 设置重试次数
-		producer.WithGroupName("order_srv_1"), // 设置生产者组名
-	)
-	if err != nil {
-		// 如果创建事务生产者失败，记录日志并返回错误。
-		zap.L().Error("NewTransactionProducer failed", zap.Error(err))
-		return nil,status.Error(codes.Internal, "NewTransactionProducer failed")
-	}
-	// 启动事务生产者
-	p.Start()
+	// 事务生产者是共用的，本地事务执行时按消息中的订单号取回 OrderEntity
+	pendingTx.Store(orderId, orderEntity)
+	defer pendingTx.Delete(orderId)
 
 	//构造事务消息的内容
 	data := model.OrderDetail{
@@ -79,27 +80,42 @@ func Create(ctx context.Context, param *proto.CreateOrderReq) (*proto.Response,
 		Topic: orderEntity.Topic, // 事务消息的主题，用于创建订单
 		Body:  b,
 	}
+	msg.WithProperty(_orderIdProperty, strconv.FormatInt(orderId, 10))
+
+	// 把 trace 上下文写入消息属性，本地事务和后续的消费者都能接上这条链路
+	tracing.InjectMessage(ctx, msg)
 
 	//发送事务消息
-	res, err := p.SendMessageInTransaction(ctx, msg)
+	start := time.Now()
+	res, err := mq.TxProducer.SendMessageInTransaction(ctx, msg)
 	if err != nil {
 		// 如果发送事务消息失败，记录日志并返回错误。
-		zap.L().Error("SendMessageInTransaction failed", zap.Error(err))
+		logger.Ctx(ctx).Error("SendMessageInTransaction failed", zap.Error(err))
+		metrics.TxMessageDuration.WithLabelValues(msg.Topic, "error").Observe(time.Since(start).Seconds())
+		metrics.OrdersFailed.WithLabelValues("send_tx_message").Inc()
 		return nil,status.Error(codes.Internal, "create order failed")
 	}
+	metrics.TxMessageDuration.WithLabelValues(msg.Topic, txStateLabel(res.State)).Observe(time.Since(start).Seconds())
 	// 根据事务消息的响应状态和Topic判断订单创建是否成功
 	if res.State == primitive.CommitMessageState {
 		// 如果事务消息提交成功，根据Topic返回不同的响应
-		if orderEntity.Topic == config.Conf.RocketMqConfig.Topic.CreateOderSuccessfully {
+		if orderEntity.Topic == config.Get().RocketMqConfig.Topic.CreateOrderSuccessfully {
+			metrics.OrdersCreated.Inc()
 			return &proto.Response{Success: true, Message: "Order created successfully"}, nil
-		} else if orderEntity.Topic == config.Conf.RocketMqConfig.Topic.PayTimeOut {
+		} else if orderEntity.Topic == config.Get().RocketMqConfig.Topic.PayTimeOut {
 			return nil, status.Error(codes.Internal, "Order creation failed due to timeout")
-		} else if orderEntity.Topic == config.Conf.RocketMqConfig.Topic.StockRollback {
+		} else if orderEntity.Topic == config.Get().RocketMqConfig.Topic.StockRollback {
 			return nil, status.Error(codes.Internal, "Order creation failed")
 		}
 	}
 
 	if res.State == primitive.RollbackMessageState {
+		// 优惠券不可用、商品价格有误等错误原样返回，由 handler 转换为对应的错误码
+		if errors.Is(orderEntity.err, errno.ErrInvalidCoupon) ||
+			errors.Is(orderEntity.err, errno.ErrCouponNotApplicable) ||
+			errors.Is(orderEntity.err, errno.ErrInvalidPrice) {
+			return nil, orderEntity.err
+		}
 		return nil, status.Error(codes.Internal, "create order failed")
 	}
 
@@ -107,22 +123,69 @@ func Create(ctx context.Context, param *proto.CreateOrderReq) (*proto.Response,
 
 }
 
+// txStateLabel 事务消息状态转换为监控指标的标签值
+func txStateLabel(state primitive.LocalTransactionState) string {
+	switch state {
+	case primitive.CommitMessageState:
+		return "commit"
+	case primitive.RollbackMessageState:
+		return "rollback"
+	default:
+		return "unknown"
+	}
+}
+
+
+// _orderIdProperty 事务消息中保存订单号的属性，本地事务执行和回查时据此找到订单
+const _orderIdProperty = "ORDER_ID"
+
+// pendingTx 正在发送事务消息的订单，key 为订单号，value 为 *OrderEntity
+var pendingTx sync.Map
+
+// TxListener 共用的事务生产者（mq.TxProducer）的监听器，按消息中的订单号把本地事务分发给对应的 OrderEntity
+type TxListener struct{}
+
+func (TxListener) ExecuteLocalTransaction(msg *primitive.Message) primitive.LocalTransactionState {
+	orderId := txOrderId(msg)
+	v, ok := pendingTx.Load(orderId)
+	if !ok {
+		zap.L().Error("ExecuteLocalTransaction order not found", zap.Int64("order_id", orderId))
+		return primitive.RollbackMessageState
+	}
+	return v.(*OrderEntity).ExecuteLocalTransaction(msg)
+}
+
+// CheckLocalTransaction 回查时发送消息的请求可能已经结束（甚至是其他实例发送的），只按订单号查询
+func (TxListener) CheckLocalTransaction(msg *primitive.MessageExt) primitive.LocalTransactionState {
+	o := &OrderEntity{OrderId: txOrderId(&msg.Message)}
+	return o.CheckLocalTransaction(msg)
+}
+
+// txOrderId 事务消息中的订单号，没有时返回 0
+func txOrderId(msg *primitive.Message) int64 {
+	orderId, _ := strconv.ParseInt(msg.GetProperty(_orderIdProperty), 10, 64)
+	return orderId
+}
 
 // ExecuteLocalTransaction 是 RocketMQ 事务消息的本地事务执行逻辑。
 // 当发送事务消息（half-message）成功后，RocketMQ 会调用此方法。
-func (o *OrderEntity) ExecuteLocalTransaction(*primitive.Message) primitive.LocalTransactionState {
-	fmt.Println("in ExecuteLocalTransaction...")
+func (o *OrderEntity) ExecuteLocalTransaction(txMsg *primitive.Message) primitive.LocalTransactionState {
+	// 从事务消息中恢复 CreateOrder 请求的 trace 上下文
+	ctx, span := tracing.Tracer().Start(tracing.ExtractMessage(context.Background(), txMsg), "ExecuteLocalTransaction")
+	defer span.End()
+	ctx = logger.NewContext(ctx, zap.Int64("order_id", o.OrderId), logger.TraceField(ctx))
+	ctx = auth.NewContext(ctx, o.caller)
+	logger.Ctx(ctx).Debug("in ExecuteLocalTransaction...")
 
 	// 参数校验：如果 Param 为空，说明事务消息的上下文不完整，直接返回 Rollback 状态。
 	if o.Param == nil {
-		zap.L().Error("ExecuteLocalTransaction param is nil")
+		logger.Ctx(ctx).Error("ExecuteLocalTransaction param is nil")
 		o.err = status.Error(codes.Internal, "invalid OrderEntity")
 		return primitive.RollbackMessageState
 	}
 
 	// 获取订单请求参数
 	param := o.Param
-	ctx := context.Background()
 
 	// 1. 查询商品金额（营销）--> RPC连接 goods_service
 	// 调用 goods_service 获取商品详情，包括价格。
@@ -132,14 +195,30 @@ func (o *OrderEntity) ExecuteLocalTransaction(*primitive.Message) primitive.Loca
 	})
 	if err != nil {
 		// 如果查询商品失败，记录日志并返回 Rollback 状态，表示本地事务失败。
-		zap.L().Error("GoodsCli.GetGoodsDetail failed", zap.Error(err))
+		logger.Ctx(ctx).Error("GoodsCli.GetGoodsDetail failed", zap.Error(err))
+		metrics.OrdersFailed.WithLabelValues("goods_detail").Inc()
 		o.err = status.Error(codes.Internal, err.Error())
 		return primitive.RollbackMessageState
 	}
 
-	// 将价格字符串转换为整型
-	payAmountStr := goodsDetail.Price
-	payAmount, _ := strconv.ParseInt(payAmountStr, 10, 64)
+	// 服务端定价：数量、满减、优惠券、运费
+	quote, err := priceGoods(ctx, goodsDetail, param.UserId, param.Num, param.CouponCode)
+	if err != nil {
+		logger.Ctx(ctx).Error("priceGoods failed", zap.Error(err))
+		metrics.OrdersFailed.WithLabelValues("pricing").Inc()
+		o.err = err
+		return primitive.RollbackMessageState
+	}
+
+	// 锁定优惠券，每张券只能被一个订单使用
+	// 从这里开始如果本地事务执行失败就需要释放优惠券
+	err = coupon.Reserve(ctx, param.UserId, quote.CouponCode, o.OrderId)
+	if err != nil {
+		logger.Ctx(ctx).Error("coupon.Reserve failed", zap.Error(err))
+		metrics.OrdersFailed.WithLabelValues("coupon").Inc()
+		o.err = err
+		return primitive.RollbackMessageState
+	}
 
 	// 2. 库存校验及扣减  --> RPC连接 stock_service
 	// 调用 stock_service 扣减库存。
@@ -151,20 +230,40 @@ func (o *OrderEntity) ExecuteLocalTransaction(*primitive.Message) primitive.Loca
 
 	if err != nil {
 		// 如果库存扣减失败，记录日志并返回 Rollback 状态，表示本地事务失败。
-		zap.L().Error("StockCli.ReduceStock failed", zap.Error(err))
+		logger.Ctx(ctx).Error("StockCli.ReduceStock failed", zap.Error(err))
+		o.releaseCoupon(ctx)
+		metrics.OrdersFailed.WithLabelValues("reduce_stock").Inc()
 		o.err = status.Error(codes.Internal, "ReduceStock failed")
 		return primitive.RollbackMessageState
 	}
 
 	// 代码能执行到这里说明 扣减库存成功了，
 	// 从这里开始如果本地事务执行失败就需要回滚库存
+	// 记录库存台账，后续所有的库存回滚都以台账为准
+	err = store.Repo().RecordStockDeduction(ctx, o.OrderId, param.GoodsId, int64(param.Num))
+	if err != nil {
+		// 台账没有写入时不能创建订单，否则后续无法保证只回滚一次
+		logger.Ctx(ctx).Error("RecordStockDeduction failed", zap.Error(err))
+		_, errRollback := rpc.StockCli.RollbackStock(ctx, &proto.ReduceStockInfo{
+			GoodsId: param.GoodsId,
+			Num:     int64(param.Num),
+			OrderId: o.OrderId,
+		})
+		if errRollback != nil {
+			logger.Ctx(ctx).Error("StockCli.RollbackStock failed", zap.Error(errRollback))
+		}
+		o.releaseCoupon(ctx)
+		metrics.OrdersFailed.WithLabelValues("stock_ledger").Inc()
+		o.err = status.Error(codes.Internal, "create order failed")
+		return primitive.RollbackMessageState
+	}
 
 	// 3. 创建订单
 	// 构建订单数据并写入 MySQL 数据库。
 	orderData := model.Order{
 		OrderId:        o.OrderId,
 		UserId:         param.UserId,
-		PayAmount:      payAmount,
+		PayAmount:      quote.PayAmount,
 		ReceiveAddress: param.Address,
 		ReceiveName:    param.Name,
 		ReceivePhone:   param.Phone,
@@ -177,24 +276,36 @@ func (o *OrderEntity) ExecuteLocalTransaction(*primitive.Message) primitive.Loca
 		Num:     int64(param.Num),
 		Title:     goodsDetail.Title,
 		Status: 	"pending",  //待支付
-		Price:     payAmount,
+		Price:     quote.UnitPrice,
 		Brief:     goodsDetail.Brief,
-		PayAmount: payAmount,
+		PayAmount: quote.PayAmount,
+
+		Subtotal:          quote.Subtotal,
+		PromotionDiscount: quote.PromotionDiscount,
+		CouponCode:        quote.CouponCode,
+		CouponDiscount:    quote.CouponDiscount,
+		ShippingFee:       quote.ShippingFee,
 	}
 
 	// 使用事务创建订单和订单详情记录。
-	err = mysql.CreateOrderWithTransation(ctx, &orderData, &orderDetail)
+	err = cache.CreateOrderWithTransation(ctx, &orderData, &orderDetail)
 	if err != nil {
 		// 如果订单创建失败，发送一条状态为“order_failed”的消息
 		// 记录日志并返回 Rollback 状态，表示本地事务失败。
-		o.Topic = config.Conf.RocketMqConfig.Topic.StockRollback
-		msg := primitive.NewMessage(o.Topic, []byte(fmt.Sprintf(`{"orderId":%d,"reason":"order creation failed"}`, o.OrderId)))
-		_, errSend := mq.Producer.SendSync(context.Background(), msg)
+		// 库存回滚消息同样要先在台账中登记，避免重复回滚
+		o.Topic = config.Get().RocketMqConfig.Topic.StockRollback
+		_, errSend := compensateStock(ctx, o.OrderId, param.GoodsId, int64(param.Num), "create_failed", func(ctx context.Context) error {
+			msg := primitive.NewMessage(o.Topic, []byte(fmt.Sprintf(`{"orderId":%d,"reason":"order creation failed"}`, o.OrderId)))
+			tracing.InjectMessage(ctx, msg)
+			_, err := mq.Producer.SendSync(ctx, msg)
+			return err
+		})
 		if errSend != nil {
-			zap.L().Error("send order_failed msg failed", zap.Error(errSend))
-			
+			logger.Ctx(ctx).Error("send order_failed msg failed", zap.Error(errSend))
 		}
-		zap.L().Error("CreateOrderWithTransation failed", zap.Error(err))
+		logger.Ctx(ctx).Error("CreateOrderWithTransation failed", zap.Error(err))
+		o.releaseCoupon(ctx)
+		metrics.OrdersFailed.WithLabelValues("create_order").Inc()
 		return primitive.RollbackMessageState
 	}
 
@@ -207,50 +318,80 @@ func (o *OrderEntity) ExecuteLocalTransaction(*primitive.Message) primitive.Loca
 		Status:  "pending",  //待支付    //实际应该根据支付服务的反馈修改
 	}
 	b, _ := json.Marshal(data)
-	o.Topic = config.Conf.RocketMqConfig.Topic.PayTimeOut
+	o.Topic = config.Get().RocketMqConfig.Topic.PayTimeOut
 	msgTimeout := primitive.NewMessage(o.Topic, b) // 设置消息的Topic: order_timeout
-	msgTimeout.WithDelayTimeLevel(3) // 设置延迟级别（例如 10s）
+	msgTimeout.WithDelayTimeLevel(config.Get().RocketMqConfig.PayTimeoutDelayLevel) // 设置延迟级别（默认 3，即 10s）
 	//同步发送延迟消息,会阻塞当前线程，知道消息发送成功或失败
 
 	//订单超时的检测通过rocketmq自带的延迟消息机制完成，在订单创建成功后，系统会发布一条延迟信息，用于在指定时间后检查订单是否超时
 	//这条消息会在设定的时间后被 RocketMQ 触发，发送到指定的消费者（例如订单服务的超时处理模块）。
 	//发送一条状态为“timeout”的消息
 	// 同步发送延迟消息，会阻塞当前线程，直到消息发送成功或失败
-	_, err = mq.Producer.SendSync(context.Background(), msgTimeout)
+	tracing.InjectMessage(ctx, msgTimeout)
+	_, err = mq.Producer.SendSync(ctx, msgTimeout)
 	if err != nil {
-		// 如果发送延迟消息失败，发送一条状态为“order_failed”的消息
+		// 如果发送延迟消息失败，订单没有办法超时关闭，取消订单并回滚库存、释放优惠券
 		// 记录日志并返回 Rollback 状态。
-		o.Topic = config.Conf.RocketMqConfig.Topic.PayTimeOut
-		msg := primitive.NewMessage(o.Topic, []byte(fmt.Sprintf(`{"orderId":%d,"reason":"timeout message send failed"}`, o.OrderId)))
-		_, errSend := mq.Producer.SendSync(context.Background(), msg)
-		if errSend != nil {
-			zap.L().Error("send order_failed msg failed", zap.Error(errSend))
-		}
-		zap.L().Error("send delay msg failed", zap.Error(err))
+		logger.Ctx(ctx).Error("send delay msg failed", zap.Error(err))
+		o.cancelCreatedOrder(ctx, "超时消息发送失败")
+		metrics.OrdersFailed.WithLabelValues("timeout_message").Inc()
 		return primitive.RollbackMessageState
 	}
 
 	//如果本地事务成功，提交订单创建成功的消息到Rocketmq
 	//发送一条状态为“success”的消息
 	// 如果本地事务成功，发送一条状态为“order_success”的消息
-	o.Topic = config.Conf.RocketMqConfig.Topic.CreateOderSuccessfully
+	o.Topic = config.Get().RocketMqConfig.Topic.CreateOrderSuccessfully
 	msgSuccess := primitive.NewMessage(o.Topic, []byte(fmt.Sprintf(`{"orderId":%d,"status":"success"}`, o.OrderId)))
-	_, err = mq.Producer.SendSync(context.Background(), msgSuccess)
+	tracing.InjectMessage(ctx, msgSuccess)
+	_, err = mq.Producer.SendSync(ctx, msgSuccess)
 	if err != nil {
-		zap.L().Error("send order success msg failed", zap.Error(err))
-		
-			return primitive.RollbackMessageState
-		}
+		// 订单已经创建，超时消息也已经发出，只是通知失败，订单仍然视为创建成功
+		logger.Ctx(ctx).Error("send order success msg failed", zap.Error(err))
+	}
 	// 如果本地事务成功，返回 Commit 状态，表示事务消息可以提交。
 	return primitive.CommitMessageState
 }
 
 
+// cancelCreatedOrder 订单已经写入数据库后本地事务失败时取消订单，再回滚库存、释放优惠券
+// 取消失败时订单仍然是待支付，不回滚库存，留给超时扫描任务关闭
+func (o *OrderEntity) cancelCreatedOrder(ctx context.Context, reason string) {
+	ctx = context.WithoutCancel(ctx)
+	err := cache.UpdateOrderStatus(ctx, &model.OrderDetail{
+		OrderId: o.OrderId,
+		Status:  model.OrderStatusCancelled,
+	}, model.StatusChange{Source: model.EventSourceCreateFailed, Reason: reason})
+	if err != nil {
+		logger.Ctx(ctx).Error("cancel order failed", zap.Error(err))
+		return
+	}
+	_, err = compensateStock(ctx, o.OrderId, o.Param.GoodsId, int64(o.Param.Num), "create_failed", func(ctx context.Context) error {
+		_, err := rpc.StockCli.RollbackStock(ctx, &proto.ReduceStockInfo{
+			GoodsId: o.Param.GoodsId,
+			Num:     int64(o.Param.Num),
+			OrderId: o.OrderId,
+		})
+		return err
+	})
+	if err != nil {
+		logger.Ctx(ctx).Error("rollback stock of cancelled order failed", zap.Error(err))
+	}
+	o.releaseCoupon(ctx)
+}
+
+// releaseCoupon 本地事务失败时释放订单锁定的优惠券
+func (o *OrderEntity) releaseCoupon(ctx context.Context) {
+	if err := coupon.Release(context.WithoutCancel(ctx), o.OrderId); err != nil {
+		logger.Ctx(ctx).Error("coupon.Release failed", zap.Error(err))
+	}
+}
+
 // CheckLocalTransaction 是 RocketMQ 事务消息的状态回查逻辑。
 // 当 RocketMQ 在发送事务消息后未收到明确的提交或回滚响应时，会调用此方法回查本地事务的状态。
 func (o *OrderEntity) CheckLocalTransaction(*primitive.MessageExt) primitive.LocalTransactionState {
 	// 查询订单是否创建成功。
-	_, err := mysql.QueryOrder(context.Background(), o.OrderId)
+	_, err := store.Repo().QueryOrder(context.Background(), o.OrderId)
 	if err == gorm.ErrRecordNotFound {
 		// 如果订单未创建成功，返回 Commit 状态，表示需要回滚库存。
 		return primitive.CommitMessageState
@@ -0,0 +1,258 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"order_service/auth"
+	"order_service/biz/coupon"
+	"order_service/biz/pricing"
+	"order_service/dao/cache"
+	"order_service/dao/redis"
+	"order_service/dao/store"
+	"order_service/errno"
+	"order_service/model"
+	"order_service/proto"
+	"order_service/rpc"
+
+	"gorm.io/gorm"
+)
+
+const (
+	_defaultPageSize = 10
+	_maxPageSize     = 100
+	// _maxSearchDepth 查询所有用户的订单时最多能翻到的条数，分库分表时每个分片都要返回前 page_num*page_size 条再合并
+	_maxSearchDepth = 1000
+)
+
+// List 分页查询用户的订单列表
+func List(ctx context.Context, req *proto.OrderListReq) (*proto.OrderListResp, error) {
+	pageNum, pageSize := int(req.GetPageNum()), int(req.GetPageSize())
+	if pageNum <= 0 {
+		pageNum = 1
+	}
+	if pageSize <= 0 {
+		pageSize = _defaultPageSize
+	}
+	if pageSize > _maxPageSize {
+		pageSize = _maxPageSize
+	}
+
+	orders, total, err := cache.QueryOrderList(ctx, req.GetUserId(), (pageNum-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	orderIds := make([]int64, 0, len(orders))
+	for _, o := range orders {
+		orderIds = append(orderIds, o.OrderId)
+	}
+	details, err := cache.QueryOrderDetails(ctx, orderIds)
+	if err != nil {
+		return nil, err
+	}
+	detailMap := make(map[int64]model.OrderDetail, len(details))
+	for _, d := range details {
+		detailMap[d.OrderId] = d
+	}
+
+	resp := &proto.OrderListResp{
+		Total: int32(total),
+		Data:  make([]*proto.OrderInfo, 0, len(orders)),
+	}
+	for _, o := range orders {
+		resp.Data = append(resp.Data, toOrderInfo(o, detailMap[o.OrderId]))
+	}
+	return resp, nil
+}
+
+// Search 按条件分页查询所有用户的订单，供运维后台使用
+// 不指定用户时查询所有分片后合并，翻页超过 _maxSearchDepth 条时返回 ErrPageTooDeep，需要缩小查询条件
+func Search(ctx context.Context, req *proto.SearchOrdersReq) (*proto.OrderListResp, error) {
+	pageNum, pageSize := int(req.GetPageNum()), int(req.GetPageSize())
+	if pageNum <= 0 {
+		pageNum = 1
+	}
+	if pageSize <= 0 {
+		pageSize = _defaultPageSize
+	}
+	if pageSize > _maxPageSize {
+		pageSize = _maxPageSize
+	}
+	if pageNum*pageSize > _maxSearchDepth {
+		return nil, errno.ErrPageTooDeep
+	}
+
+	filter := model.OrderFilter{UserId: req.GetUserId()}
+	if req.GetStatus() != 0 {
+		st, ok := model.StatusFromCode(req.GetStatus())
+		if !ok {
+			return nil, errno.ErrInvalidStatus
+		}
+		filter.Statuses = []string{st}
+		if model.IsUnpaidStatus(st) {
+			// 待支付对应 pending 和 unpaid 两个状态
+			filter.Statuses = []string{model.OrderStatusPending, model.OrderStatusUnpaid}
+		}
+	}
+	if req.GetCreatedAfter() > 0 {
+		filter.CreatedAfter = time.Unix(req.GetCreatedAfter(), 0)
+	}
+	if req.GetCreatedBefore() > 0 {
+		filter.CreatedBefore = time.Unix(req.GetCreatedBefore(), 0)
+	}
+
+	orders, total, err := store.Repo().SearchOrders(ctx, filter, (pageNum-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	orderIds := make([]int64, 0, len(orders))
+	for _, o := range orders {
+		orderIds = append(orderIds, o.OrderId)
+	}
+	details, err := cache.QueryOrderDetails(ctx, orderIds)
+	if err != nil {
+		return nil, err
+	}
+	detailMap := make(map[int64]model.OrderDetail, len(details))
+	for _, d := range details {
+		detailMap[d.OrderId] = d
+	}
+
+	resp := &proto.OrderListResp{
+		Total: int32(total),
+		Data:  make([]*proto.OrderInfo, 0, len(orders)),
+	}
+	for _, o := range orders {
+		resp.Data = append(resp.Data, toOrderInfo(o, detailMap[o.OrderId]))
+	}
+	return resp, nil
+}
+
+// Detail 查询订单详情，只能查询属于当前用户的订单
+func Detail(ctx context.Context, req *proto.OrderDetailReq) (*proto.OrderDetailInfo, error) {
+	o, err := cache.QueryOrder(ctx, req.GetOrderId())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errno.ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if req.GetUserId() > 0 && o.UserId != req.GetUserId() {
+		return nil, errno.ErrOrderNotFound
+	}
+
+	detail, err := cache.QueryOrderDetail(ctx, o.OrderId)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &proto.OrderDetailInfo{OrderInfo: toOrderInfo(o, detail)}, nil
+}
+
+// History 查询订单的状态变化历史，只能查询属于当前用户的订单
+func History(ctx context.Context, req *proto.OrderHistoryReq) (*proto.OrderHistoryResp, error) {
+	o, err := cache.QueryOrder(ctx, req.GetOrderId())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errno.ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if req.GetUserId() > 0 && o.UserId != req.GetUserId() {
+		return nil, errno.ErrOrderNotFound
+	}
+
+	events, err := store.Repo().QueryOrderEvents(ctx, o.OrderId)
+	if err != nil {
+		return nil, err
+	}
+	resp := &proto.OrderHistoryResp{
+		OrderId: o.OrderId,
+		Events:  make([]*proto.OrderEvent, 0, len(events)),
+	}
+	for _, e := range events {
+		resp.Events = append(resp.Events, &proto.OrderEvent{
+			FromStatus: model.StatusCode(e.FromStatus),
+			ToStatus:   model.StatusCode(e.ToStatus),
+			Actor:      e.Actor,
+			Source:     e.Source,
+			Reason:     e.Reason,
+			CreatedAt:  e.CreateAt.Unix(),
+		})
+	}
+	return resp, nil
+}
+
+// UpdateStatus 更新订单状态，持有订单锁执行，只能修改属于当前用户的订单
+// 状态只能按 model.CanTransition 变化，已经结束的订单不能再修改；
+// 开启鉴权时普通用户只能取消自己待支付的订单，支付、发货、完成由支付服务等使用运维身份调用。
+// 支付后核销订单锁定的优惠券，取消或超时回滚库存并释放优惠券
+func UpdateStatus(ctx context.Context, req *proto.OrderStatus) error {
+	st, ok := model.StatusFromCode(req.GetStatus())
+	if !ok {
+		return errno.ErrInvalidStatus
+	}
+	if auth.Enabled() && !auth.IsAdmin(ctx) && st != model.OrderStatusCancelled {
+		return errno.ErrStatusNotAllowed
+	}
+	return redis.WithOrderLock(ctx, req.GetOrderId(), func(ctx context.Context, token int64) error {
+		// 锁内从主库读取最新状态，检查通过后才核销或释放优惠券
+		order, err := store.Repo().QueryOrderDetail(store.WithPrimary(ctx), req.GetOrderId())
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !auth.CanAccess(ctx, order.UserId)) {
+			return errno.ErrOrderNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !model.CanTransition(order.Status, st) {
+			return errno.ErrInvalidStatus
+		}
+		if order.Status == st {
+			return nil
+		}
+
+		switch st {
+		case model.OrderStatusPaid:
+			err = coupon.Confirm(ctx, req.GetOrderId())
+		case model.OrderStatusCancelled, model.OrderStatusTimeout:
+			// 和超时关单一样按库存台账回滚库存，再释放优惠券
+			_, err = compensateStock(ctx, order.OrderId, order.GoodsId, order.Num, "rpc", func(ctx context.Context) error {
+				_, err := rpc.StockCli.RollbackStock(ctx, &proto.ReduceStockInfo{
+					GoodsId: order.GoodsId,
+					Num:     order.Num,
+					OrderId: order.OrderId,
+				})
+				return err
+			})
+			if err == nil {
+				err = coupon.Release(ctx, req.GetOrderId())
+			}
+		}
+		if err != nil {
+			return err
+		}
+		return cache.UpdateOrderStatus(ctx, &model.OrderDetail{
+			OrderId:    req.GetOrderId(),
+			Status:     st,
+			FenceToken: token,
+		}, model.StatusChange{Source: model.EventSourceRPC, Reason: req.GetReason()})
+	})
+}
+
+// toOrderInfo 数据库模型转换为接口返回的订单信息
+func toOrderInfo(o model.Order, d model.OrderDetail) *proto.OrderInfo {
+	return &proto.OrderInfo{
+		OrderId:   o.OrderId,
+		UserId:    o.UserId,
+		Status:    model.StatusCode(d.Status),
+		PayAmount: o.PayAmount,
+		GoodsDetail: &proto.GoodsDetail{
+			GoodsId: d.GoodsId,
+			Title:   d.Title,
+			Price:   pricing.FormatYuan(d.Price), // 和 goods_service 一致，以元为单位
+			Brief:   d.Brief,
+		},
+	}
+}
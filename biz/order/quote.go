@@ -0,0 +1,55 @@
+package order
+
+import (
+	"context"
+
+	"order_service/biz/pricing"
+	"order_service/errno"
+	"order_service/logger"
+	"order_service/proto"
+	"order_service/rpc"
+
+	"go.uber.org/zap"
+)
+
+// Quote 订单试算，计算价格明细但不创建订单
+func Quote(ctx context.Context, req *proto.QuoteOrderReq) (*proto.OrderQuote, error) {
+	goodsDetail, err := rpc.GoodsCli.GetGoodsDetail(ctx, &proto.GetGoodsDetailReq{
+		GoodsId: req.GetGoodsId(),
+		UserId:  req.GetUserId(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	q, err := priceGoods(ctx, goodsDetail, req.GetUserId(), req.GetNum(), req.GetCouponCode())
+	if err != nil {
+		return nil, err
+	}
+	return &proto.OrderQuote{
+		GoodsId:           q.GoodsId,
+		Num:               int32(q.Num),
+		UnitPrice:         q.UnitPrice,
+		Subtotal:          q.Subtotal,
+		PromotionName:     q.PromotionName,
+		PromotionDiscount: q.PromotionDiscount,
+		CouponCode:        q.CouponCode,
+		CouponDiscount:    q.CouponDiscount,
+		ShippingFee:       q.ShippingFee,
+		PayAmount:         q.PayAmount,
+	}, nil
+}
+
+// priceGoods 按商品详情中的售价计算订单价格，订单试算和创建订单共用
+func priceGoods(ctx context.Context, goodsDetail *proto.GoodsDetail, userId int64, num int32, couponCode string) (*pricing.Quote, error) {
+	unitPrice, err := pricing.ParseYuan(goodsDetail.GetPrice())
+	if err != nil {
+		logger.Ctx(ctx).Error("invalid goods price", zap.Int64("goods_id", goodsDetail.GetGoodsId()), zap.Error(err))
+		return nil, errno.ErrInvalidPrice
+	}
+	return pricing.Calculate(ctx, pricing.Item{
+		UserId:    userId,
+		GoodsId:   goodsDetail.GetGoodsId(),
+		UnitPrice: unitPrice,
+		Num:       int64(num),
+	}, couponCode)
+}
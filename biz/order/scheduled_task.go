@@ -2,10 +2,10 @@ package order
 
 import (
 	"context"
-	"order_service/dao/mysql"
+	"order_service/dao/store"
+	"order_service/logger"
 	"order_service/model"
-	"order_service/proto"
-	"order_service/rpc"
+	"order_service/tracing"
 	"time"
 
 	"go.uber.org/zap"
@@ -13,7 +13,7 @@ import (
 
 // StartTimeoutScanner 启动定时任务，扫描并处理超时订单
 func StartTimeoutScanner(ctx context.Context) {
-	zap.L().Info("Starting order timeout scanner")
+	logger.Ctx(ctx).Info("Starting order timeout scanner")
 
 	// 定时任务：每5分钟执行一次
 	ticker := time.NewTicker(5 * time.Minute)
@@ -22,7 +22,7 @@ func StartTimeoutScanner(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
-			zap.L().Info("Order timeout scanner stopped")
+			logger.Ctx(ctx).Info("Order timeout scanner stopped")
 			return
 		case <-ticker.C:
 			scanAndProcessTimeoutOrders()
@@ -31,49 +31,59 @@ func StartTimeoutScanner(ctx context.Context) {
 }
 
 // scanAndProcessTimeoutOrders 扫描并处理超时订单
+// 分库时每个分片库的自增ID独立，逐个分片库扫描
 func scanAndProcessTimeoutOrders() {
-	ctx := context.Background()
+	ctx, span := tracing.Tracer().Start(context.Background(), "scanTimeoutOrders")
+	defer span.End()
+	ctx = logger.NewContext(ctx, logger.TraceField(ctx))
 
+	for _, db := range store.Shards() {
+		scanDatabase(logger.NewContext(ctx, zap.String("database", db.Name()), zap.String("table", db.Table())), db)
+	}
+}
+
+// scanDatabase 扫描一个库中的超时订单
+func scanDatabase(ctx context.Context, db store.Shard) {
 	// 计算一周前的时间
 	oneWeekAgo := time.Now().Add(-7 * 24 * time.Hour)
 
 	// 获取一周前的订单ID最小值
-	minOrderIdOneWeekAgo, err := mysql.GetMinOrderIdAfterTime(ctx, oneWeekAgo)
+	minOrderIdOneWeekAgo, err := db.GetMinOrderIdAfterTime(ctx, oneWeekAgo)
 	if err != nil {
-		zap.L().Error("Failed to get min order ID one week ago", zap.Error(err))
+		logger.Ctx(ctx).Error("Failed to get min order ID one week ago", zap.Error(err))
 		return
 	}
 
 	// 获取订单ID分片参数，只针对一周内的订单
 	// 获取订单ID分片参数，只针对大于minOrderIdOneWeekAgo的订单
-	shardParams, err := mysql.GetShardParams(ctx, minOrderIdOneWeekAgo)
+	shardParams, err := db.GetShardParams(ctx, minOrderIdOneWeekAgo)
 	if err != nil {
-		zap.L().Error("Failed to get shard parameters", zap.Error(err))
+		logger.Ctx(ctx).Error("Failed to get shard parameters", zap.Error(err))
 		return
 	}
 
 	for _, param := range shardParams {
-		processShard(ctx, param)
+		processShard(ctx, db, param)
 	}
 }
 
 // processShard 处理单个分片
-func processShard(ctx context.Context, param model.ShardParam) {
-	zap.L().Info("Processing shard", zap.Int("ShardID", param.ShardID))
+func processShard(ctx context.Context, db store.Shard, param model.ShardParam) {
+	logger.Ctx(ctx).Info("Processing shard", zap.Int("ShardID", param.ShardID))
 
 	// 查询当前分片的超时订单
-	timeoutOrders, err := mysql.QueryTimeoutOrdersByShard(ctx, param.StartID, param.EndID, time.Now().Add(-30*time.Minute))
+	timeoutOrders, err := db.QueryTimeoutOrdersByShard(ctx, param.StartID, param.EndID, time.Now().Add(-30*time.Minute))
 	if err != nil {
-		zap.L().Error("Failed to query timeout orders for shard", zap.Error(err), zap.Int("ShardID", param.ShardID))
+		logger.Ctx(ctx).Error("Failed to query timeout orders for shard", zap.Error(err), zap.Int("ShardID", param.ShardID))
 		return
 	}
 
 	if len(timeoutOrders) == 0 {
-		zap.L().Info("No timeout orders found for shard", zap.Int("ShardID", param.ShardID))
+		logger.Ctx(ctx).Info("No timeout orders found for shard", zap.Int("ShardID", param.ShardID))
 		return
 	}
 
-	zap.L().Info("Found timeout orders for shard", zap.Int("count", len(timeoutOrders)), zap.Int("ShardID", param.ShardID))
+	logger.Ctx(ctx).Info("Found timeout orders for shard", zap.Int("count", len(timeoutOrders)), zap.Int("ShardID", param.ShardID))
 
 	for _, order := range timeoutOrders {
 		processTimeoutOrder(ctx, order)
@@ -82,29 +92,13 @@ func processShard(ctx context.Context, param model.ShardParam) {
 
 // processTimeoutOrder 处理单个超时订单
 func processTimeoutOrder(ctx context.Context, order model.OrderDetail) {
-	zap.L().Info("Processing timeout order", zap.Int64("OrderId", order.OrderId))
-
-	// 1. 回滚库存
-	_, err := rpc.StockCli.RollbackStock(ctx, &proto.ReduceStockInfo{
-		GoodsId: order.GoodsId,
-		Num:     order.Num,
-		OrderId: order.OrderId,
-	})
-	if err != nil {
-		zap.L().Error("Failed to rollback stock", zap.Error(err), zap.Int64("OrderId", order.OrderId))
-		return
-	}
+	ctx = logger.NewContext(ctx, zap.Int64("order_id", order.OrderId))
+	logger.Ctx(ctx).Info("Processing timeout order")
 
-	// 2. 更新订单状态为“已超时”
-	order.Status = "timeout"
-	err = mysql.UpdateOrderStatus(ctx, &order)
-	if err != nil {
-		zap.L().Error("Failed to update order status to timeout", zap.Error(err), zap.Int64("OrderId", order.OrderId))
+	if err := closeTimeoutOrder(ctx, order.OrderId, "scanner"); err != nil {
+		logger.Ctx(ctx).Error("Failed to close timeout order", zap.Error(err))
 		return
 	}
 
-	// 3. 发送超时通知（可选）
-	// utils.SendOrderTimeoutNotification(order.OrderId)
-
-	zap.L().Info("Order processed successfully", zap.Int64("OrderId", order.OrderId))
+	logger.Ctx(ctx).Info("Order processed successfully")
 }
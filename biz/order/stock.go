@@ -0,0 +1,40 @@
+package order
+
+import (
+	"context"
+
+	"order_service/dao/store"
+	"order_service/logger"
+	"order_service/metrics"
+
+	"go.uber.org/zap"
+)
+
+// compensateStock 按库存台账执行一次库存补偿，同一笔扣减只会补偿一次
+// rollback 为具体的补偿动作（调用 stock_service 回滚，或者发送库存回滚消息），
+// 返回 false 表示这笔扣减已经补偿过（或者正在补偿），本次跳过。
+func compensateStock(ctx context.Context, orderId, goodsId, num int64, source string,
+	rollback func(ctx context.Context) error) (bool, error) {
+	claimed, err := store.Repo().ClaimStockRollback(ctx, orderId, goodsId, num, source)
+	if err != nil {
+		logger.Ctx(ctx).Error("ClaimStockRollback failed", zap.Error(err))
+		return false, err
+	}
+	if !claimed {
+		logger.Ctx(ctx).Info("Stock already rolled back, skipping", zap.String("source", source))
+		metrics.StockRollbackSkipped.WithLabelValues(source).Inc()
+		return false, nil
+	}
+
+	rollbackErr := rollback(ctx)
+	// 回滚失败时恢复台账，下次重试可以重新回滚
+	if err := store.Repo().FinishStockRollback(context.WithoutCancel(ctx), orderId, goodsId, rollbackErr == nil); err != nil {
+		// 台账停留在回滚中，由对账任务发现后人工处理
+		logger.Ctx(ctx).Error("FinishStockRollback failed", zap.Bool("rolled_back", rollbackErr == nil), zap.Error(err))
+	}
+	if rollbackErr != nil {
+		return false, rollbackErr
+	}
+	metrics.OrdersRolledBack.WithLabelValues(source).Inc()
+	return true, nil
+}
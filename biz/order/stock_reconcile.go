@@ -0,0 +1,203 @@
+package order
+
+import (
+	"context"
+	"time"
+
+	"order_service/config"
+	"order_service/dao/store"
+	"order_service/logger"
+	"order_service/metrics"
+	"order_service/proto"
+	"order_service/rpc"
+	"order_service/tracing"
+
+	"go.uber.org/zap"
+)
+
+// 订单-库存对账
+// ExecuteLocalTransaction 在 ReduceStock 成功之后失败（例如进程退出）时，库存已经扣减但订单没有写入 xx_order。
+// 对账任务从 stock_service 分页拉取扣减记录，和订单表比对，
+// 找不到订单且超过 orphan_age 的扣减记录视为孤儿扣减，按库存台账回滚（保证只回滚一次）。
+
+// 孤儿扣减的处理结果
+const (
+	OrphanActionRolledBack = "rolled_back" // 已回滚
+	OrphanActionSkipped    = "skipped"     // 台账显示已经回滚过
+	OrphanActionFailed     = "failed"      // 回滚失败
+	OrphanActionDryRun     = "dry_run"     // 只报告，不回滚
+)
+
+// ReconcileOptions 对账参数
+type ReconcileOptions struct {
+	Start     time.Time // 对账的扣减时间范围 [Start, End)
+	End       time.Time
+	OrphanAge time.Duration // 扣减后超过这个时间仍然没有订单的才回滚，避免误伤正在创建的订单
+	DryRun    bool          // 只生成报告，不回滚
+	PageSize  int32         // 每次从 stock_service 拉取的记录数
+}
+
+// StockOrphan 孤儿扣减
+type StockOrphan struct {
+	OrderId    int64     `json:"order_id"`
+	GoodsId    int64     `json:"goods_id"`
+	Num        int64     `json:"num"`
+	DeductedAt time.Time `json:"deducted_at"`
+	Action     string    `json:"action"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// StockReconcileReport 对账报告
+type StockReconcileReport struct {
+	Start      time.Time     `json:"start"`
+	End        time.Time     `json:"end"`
+	Scanned    int           `json:"scanned"`     // 扫描的扣减记录数
+	Matched    int           `json:"matched"`     // 能找到订单的记录数
+	RolledBack int           `json:"rolled_back"` // stock_service 已经回滚过的记录数
+	TooRecent  int           `json:"too_recent"`  // 找不到订单，但还没超过 orphan_age 的记录数
+	Orphans    []StockOrphan `json:"orphans"`     // 孤儿扣减
+}
+
+// DefaultReconcileOptions 根据配置生成对账参数
+func DefaultReconcileOptions() ReconcileOptions {
+	cfg := config.Get().StockReconcileConfig
+	now := time.Now()
+	return ReconcileOptions{
+		Start:     now.Add(-cfg.Window),
+		End:       now,
+		OrphanAge: cfg.OrphanAge,
+		DryRun:    !cfg.AutoRollback,
+		PageSize:  cfg.PageSize,
+	}
+}
+
+// StartStockReconciler 启动订单-库存对账任务，stock_reconcile.interval 为 0 时不启动
+func StartStockReconciler(ctx context.Context) {
+	interval := config.Get().StockReconcileConfig.Interval
+	if interval <= 0 {
+		return
+	}
+	logger.Ctx(ctx).Info("Starting order-stock reconciler", zap.Duration("interval", interval))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Ctx(ctx).Info("Order-stock reconciler stopped")
+			return
+		case <-ticker.C:
+			if _, err := ReconcileStock(ctx, DefaultReconcileOptions()); err != nil {
+				logger.Ctx(ctx).Error("ReconcileStock failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// ReconcileStock 执行一次订单-库存对账
+func ReconcileStock(ctx context.Context, opts ReconcileOptions) (*StockReconcileReport, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "reconcileStock")
+	defer span.End()
+	ctx = logger.NewContext(ctx, logger.TraceField(ctx))
+
+	report := &StockReconcileReport{Start: opts.Start, End: opts.End}
+	var cursor int64
+	for {
+		resp, err := rpc.StockCli.ListDeductions(ctx, &proto.ListDeductionsReq{
+			StartTime: opts.Start.Unix(),
+			EndTime:   opts.End.Unix(),
+			Cursor:    cursor,
+			Limit:     opts.PageSize,
+		})
+		if err != nil {
+			span.RecordError(err)
+			return report, err
+		}
+		if err := reconcilePage(ctx, opts, resp.GetData(), report); err != nil {
+			span.RecordError(err)
+			return report, err
+		}
+		cursor = resp.GetNextCursor()
+		if cursor == 0 || len(resp.GetData()) == 0 {
+			break
+		}
+	}
+
+	logger.Ctx(ctx).Info("Order-stock reconciled",
+		zap.Int("scanned", report.Scanned),
+		zap.Int("matched", report.Matched),
+		zap.Int("orphans", len(report.Orphans)),
+		zap.Bool("dry_run", opts.DryRun),
+	)
+	return report, nil
+}
+
+// reconcilePage 比对一页扣减记录
+func reconcilePage(ctx context.Context, opts ReconcileOptions, deductions []*proto.StockDeduction, report *StockReconcileReport) error {
+	orderIds := make([]int64, 0, len(deductions))
+	for _, d := range deductions {
+		orderIds = append(orderIds, d.GetOrderId())
+	}
+	existing, err := store.Repo().QueryExistingOrderIds(ctx, orderIds)
+	if err != nil {
+		return err
+	}
+	found := make(map[int64]bool, len(existing))
+	for _, id := range existing {
+		found[id] = true
+	}
+
+	for _, d := range deductions {
+		report.Scanned++
+		switch {
+		case found[d.GetOrderId()]:
+			report.Matched++
+			continue
+		case d.GetRolledBack():
+			report.RolledBack++
+			continue
+		}
+		deductedAt := time.Unix(d.GetCreateTime(), 0)
+		if time.Since(deductedAt) < opts.OrphanAge {
+			report.TooRecent++
+			continue
+		}
+
+		orphan := StockOrphan{
+			OrderId:    d.GetOrderId(),
+			GoodsId:    d.GetGoodsId(),
+			Num:        d.GetNum(),
+			DeductedAt: deductedAt,
+			Action:     OrphanActionDryRun,
+		}
+		if !opts.DryRun {
+			rollbackOrphan(ctx, &orphan)
+		}
+		metrics.StockReconcileOrphans.WithLabelValues(orphan.Action).Inc()
+		report.Orphans = append(report.Orphans, orphan)
+	}
+	return nil
+}
+
+// rollbackOrphan 按台账回滚孤儿扣减
+func rollbackOrphan(ctx context.Context, orphan *StockOrphan) {
+	ctx = logger.NewContext(ctx, zap.Int64("order_id", orphan.OrderId))
+	done, err := compensateStock(ctx, orphan.OrderId, orphan.GoodsId, orphan.Num, "reconcile", func(ctx context.Context) error {
+		_, err := rpc.StockCli.RollbackStock(ctx, &proto.ReduceStockInfo{
+			GoodsId: orphan.GoodsId,
+			Num:     orphan.Num,
+			OrderId: orphan.OrderId,
+		})
+		return err
+	})
+	switch {
+	case err != nil:
+		orphan.Action, orphan.Error = OrphanActionFailed, err.Error()
+		logger.Ctx(ctx).Error("Failed to rollback orphan stock deduction", zap.Error(err))
+	case done:
+		orphan.Action = OrphanActionRolledBack
+		logger.Ctx(ctx).Warn("Orphan stock deduction rolled back", zap.Int64("goods_id", orphan.GoodsId), zap.Int64("num", orphan.Num))
+	default:
+		orphan.Action = OrphanActionSkipped
+	}
+}
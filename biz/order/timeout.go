@@ -0,0 +1,83 @@
+package order
+
+import (
+	"context"
+	"errors"
+
+	"order_service/auth"
+	"order_service/biz/coupon"
+	"order_service/dao/cache"
+	"order_service/dao/redis"
+	"order_service/dao/store"
+	"order_service/logger"
+	"order_service/metrics"
+	"order_service/model"
+	"order_service/proto"
+	"order_service/rpc"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// closeTimeoutOrder 关闭支付超时的订单：回滚库存、释放优惠券并把订单状态改为“已超时”
+// 超时消息消费者和超时扫描任务都会调用，source 为调用来源（consumer/scanner），
+// 整个过程持有订单锁，并且在锁内重新读取订单状态，订单已经被处理过时直接返回。
+func closeTimeoutOrder(ctx context.Context, orderId int64, source string) error {
+	eventSource := "timeout_" + source // model.EventSourceTimeoutConsumer / EventSourceTimeoutScanner
+	ctx = auth.NewContext(ctx, auth.System(eventSource))
+	return redis.WithOrderLock(ctx, orderId, func(ctx context.Context, token int64) error {
+		// 锁内从主库读取最新状态，不能用缓存、从库或消息里的状态
+		order, err := store.Repo().QueryOrderDetail(store.WithPrimary(ctx), orderId)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 订单创建失败，本地事务已回滚
+			logger.Ctx(ctx).Info("Order not found, ignoring timeout")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch order.Status {
+		case model.OrderStatusPending, model.OrderStatusUnpaid:
+		default:
+			// 如果订单状态不是“未支付”，记录日志并忽略
+			logger.Ctx(ctx).Info("Order already processed, ignoring timeout", zap.String("status", order.Status))
+			return nil
+		}
+		logger.Ctx(ctx).Info("Order is unpaid, processing timeout", zap.Int64("fence_token", token))
+
+		// 1. 回滚库存，台账显示已经回滚过时跳过
+		_, err = compensateStock(ctx, order.OrderId, order.GoodsId, order.Num, source, func(ctx context.Context) error {
+			_, err := rpc.StockCli.RollbackStock(ctx, &proto.ReduceStockInfo{
+				GoodsId: order.GoodsId,
+				Num:     order.Num,
+				OrderId: order.OrderId,
+			})
+			return err
+		})
+		if err != nil {
+			logger.Ctx(ctx).Error("Failed to rollback stock", zap.Error(err))
+			return err
+		}
+
+		// 2. 释放订单锁定的优惠券，没有锁定时直接返回
+		if err := coupon.Release(ctx, order.OrderId); err != nil {
+			logger.Ctx(ctx).Error("Failed to release coupon", zap.Error(err))
+			return err
+		}
+
+		// 3. 更新订单状态为“已超时”
+		order.Status = model.OrderStatusTimeout
+		order.FenceToken = token
+		err = cache.UpdateOrderStatus(ctx, &order, model.StatusChange{Source: eventSource, Reason: "支付超时"})
+		if err != nil {
+			logger.Ctx(ctx).Error("Failed to update order status to timeout", zap.Error(err))
+			return err
+		}
+		metrics.OrdersTimedOut.WithLabelValues(source).Inc()
+
+		// 4. 发送超时通知（可选）
+		// utils.SendOrderTimeoutNotification(order.OrderId)
+		return nil
+	})
+}
@@ -0,0 +1,83 @@
+package pricing
+
+import (
+	"context"
+	"strings"
+
+	"order_service/errno"
+)
+
+// 优惠券类型
+const (
+	CouponTypeAmount  = "amount"  // 立减，value 为减免金额（分）
+	CouponTypePercent = "percent" // 折扣，value 为折扣百分比，例如 90 表示 9 折
+)
+
+// Coupon 优惠券的优惠规则
+type Coupon struct {
+	Code        string
+	Type        string
+	Value       int64
+	MinAmount   int64 // 使用门槛（分），按满减后的金额计算
+	MaxDiscount int64 // 折扣券的最高减免金额（分），0 表示不限制
+}
+
+// CouponProvider 根据券码查询用户可用的优惠券，券码不存在或不可用时返回 errno.ErrInvalidCoupon
+type CouponProvider interface {
+	GetCoupon(ctx context.Context, userId int64, code string) (*Coupon, error)
+}
+
+// coupons 当前使用的优惠券来源，默认读取配置文件中的 pricing.coupons
+var coupons CouponProvider = ConfigCoupons{}
+
+// SetCouponProvider 替换优惠券来源
+func SetCouponProvider(p CouponProvider) {
+	coupons = p
+}
+
+// ConfigCoupons 配置文件中定义的优惠券，所有用户通用
+type ConfigCoupons struct{}
+
+func (ConfigCoupons) GetCoupon(_ context.Context, _ int64, code string) (*Coupon, error) {
+	for _, c := range pricingConfig().Coupons {
+		if strings.EqualFold(c.Code, code) {
+			return &Coupon{
+				Code:        c.Code,
+				Type:        c.Type,
+				Value:       c.Value,
+				MinAmount:   c.MinAmount,
+				MaxDiscount: c.MaxDiscount,
+			}, nil
+		}
+	}
+	return nil, errno.ErrInvalidCoupon
+}
+
+// discount 计算优惠券在 amount 上的减免金额，不满足使用门槛时返回 errno.ErrCouponNotApplicable
+// 数据库中发放的券没有经过配置校验，面值不合法（立减金额不大于 0、折扣不在 1~99 之间）时返回 errno.ErrInvalidCoupon，
+// 减免金额限制在 [0, amount] 内，不会增加应付金额
+func (c *Coupon) discount(amount int64) (int64, error) {
+	if amount < c.MinAmount {
+		return 0, errno.ErrCouponNotApplicable
+	}
+	var d int64
+	switch c.Type {
+	case CouponTypeAmount:
+		if c.Value <= 0 {
+			return 0, errno.ErrInvalidCoupon
+		}
+		d = c.Value
+	case CouponTypePercent:
+		if c.Value <= 0 || c.Value >= 100 {
+			return 0, errno.ErrInvalidCoupon
+		}
+		// 减免金额向下取整到分
+		d = amount * (100 - c.Value) / 100
+		if c.MaxDiscount > 0 && d > c.MaxDiscount {
+			d = c.MaxDiscount
+		}
+	default:
+		return 0, errno.ErrInvalidCoupon
+	}
+	return max(0, min(d, amount)), nil
+}
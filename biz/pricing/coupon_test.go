@@ -0,0 +1,39 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+
+	"order_service/errno"
+)
+
+// 数据库发放的券没有经过配置校验，面值不合法时拒绝使用，而不是让应付金额变多
+func TestDiscountRejectsInvalidValue(t *testing.T) {
+	for _, c := range []*Coupon{
+		{Code: "ZERO", Type: CouponTypeAmount, Value: 0},
+		{Code: "NEG", Type: CouponTypeAmount, Value: -500},
+		{Code: "FREE", Type: CouponTypePercent, Value: 0},
+		{Code: "MORE", Type: CouponTypePercent, Value: 150},
+		{Code: "FULL", Type: CouponTypePercent, Value: 100},
+		{Code: "GIFT", Type: "gift", Value: 1},
+	} {
+		if d, err := c.discount(10000); !errors.Is(err, errno.ErrInvalidCoupon) {
+			t.Errorf("%s: discount = %d, %v, want ErrInvalidCoupon", c.Code, d, err)
+		}
+	}
+}
+
+func TestDiscountClampedToAmount(t *testing.T) {
+	c := &Coupon{Code: "MINUS500", Type: CouponTypeAmount, Value: 50000}
+	if d, err := c.discount(3000); err != nil || d != 3000 {
+		t.Errorf("discount(3000) = %d, %v, want 3000", d, err)
+	}
+
+	p := &Coupon{Code: "OFF10", Type: CouponTypePercent, Value: 90, MaxDiscount: 500}
+	if d, err := p.discount(99999); err != nil || d != 500 {
+		t.Errorf("percent discount(99999) = %d, %v, want capped 500", d, err)
+	}
+	if d, err := p.discount(999); err != nil || d != 99 {
+		t.Errorf("percent discount(999) = %d, %v, want 99 rounded down", d, err)
+	}
+}
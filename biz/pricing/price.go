@@ -0,0 +1,47 @@
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseYuan 把以元为单位的十进制价格字符串（例如 "19.90"）转换为分
+// 不经过浮点数，最多两位小数，负数和非法格式返回错误
+func ParseYuan(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	yuan, fen, hasDot := strings.Cut(s, ".")
+	if len(yuan) == 0 || (hasDot && len(fen) == 0) || len(fen) > 2 {
+		return 0, fmt.Errorf("invalid price %q", s)
+	}
+	for _, c := range yuan + fen {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("invalid price %q", s)
+		}
+	}
+	y, err := strconv.ParseInt(yuan, 10, 64)
+	if err != nil || y > math.MaxInt64/100 {
+		return 0, fmt.Errorf("invalid price %q", s)
+	}
+	var f int64
+	if len(fen) > 0 {
+		f, _ = strconv.ParseInt(fen, 10, 64)
+		if len(fen) == 1 {
+			f *= 10
+		}
+	}
+	if y*100 > math.MaxInt64-f {
+		return 0, fmt.Errorf("invalid price %q", s)
+	}
+	return y*100 + f, nil
+}
+
+// FormatYuan 把分转换为以元为单位的价格字符串，例如 1990 -> "19.90"
+func FormatYuan(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
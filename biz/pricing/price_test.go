@@ -0,0 +1,49 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseYuan(t *testing.T) {
+	valid := map[string]int64{
+		"19.9":                 1990,
+		"19.90":                1990,
+		"0.05":                 5,
+		"0":                    0,
+		" 12 ":                 1200,
+		"92233720368547758.07": math.MaxInt64,
+	}
+	for in, want := range valid {
+		if got, err := ParseYuan(in); err != nil || got != want {
+			t.Errorf("ParseYuan(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"", "1.", ".5", "-1", "1.234", "1e3", "¥10"} {
+		if got, err := ParseYuan(in); err == nil {
+			t.Errorf("ParseYuan(%q) = %d, want error", in, got)
+		}
+	}
+}
+
+// 超出 int64 的金额返回错误，不能溢出成负数或者很小的金额
+func TestParseYuanOverflow(t *testing.T) {
+	for _, in := range []string{"92233720368547758.08", "92233720368547759", "99999999999999999999"} {
+		if got, err := ParseYuan(in); err == nil {
+			t.Errorf("ParseYuan(%q) = %d, want overflow error", in, got)
+		}
+	}
+}
+
+func TestFormatYuanRoundTrip(t *testing.T) {
+	for _, cents := range []int64{0, 5, 100, 1990, math.MaxInt64} {
+		s := FormatYuan(cents)
+		if back, err := ParseYuan(s); err != nil || back != cents {
+			t.Errorf("ParseYuan(FormatYuan(%d) = %q) = %d, %v", cents, s, back, err)
+		}
+	}
+	if got := FormatYuan(-150); got != "-1.50" {
+		t.Errorf("FormatYuan(-150) = %q, want -1.50", got)
+	}
+}
@@ -0,0 +1,102 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"order_service/config"
+)
+
+// 服务端定价
+// 所有金额单位都是分，计算顺序：
+// 1. 小计 = 单价 × 数量
+// 2. 满减：按小计匹配门槛最高的满减活动
+// 3. 优惠券：门槛按满减后的金额计算
+// 4. 运费：优惠后的金额达到包邮门槛免运费
+// 实付金额 = 小计 - 满减 - 优惠券 + 运费
+
+// Item 待定价的商品
+type Item struct {
+	UserId    int64
+	GoodsId   int64
+	UnitPrice int64 // 单价（分）
+	Num       int64 // 数量
+}
+
+// Quote 定价结果
+type Quote struct {
+	Item
+	Subtotal          int64  // 小计
+	PromotionName     string // 命中的满减活动
+	PromotionDiscount int64  // 满减金额
+	CouponCode        string // 使用的优惠券
+	CouponDiscount    int64  // 优惠券减免金额
+	ShippingFee       int64  // 运费
+	PayAmount         int64  // 实付金额
+}
+
+// Calculate 计算订单价格，couponCode 为空表示不使用优惠券
+func Calculate(ctx context.Context, item Item, couponCode string) (*Quote, error) {
+	if item.UnitPrice < 0 || item.Num <= 0 {
+		return nil, fmt.Errorf("invalid item: price=%d num=%d", item.UnitPrice, item.Num)
+	}
+	if item.UnitPrice > 0 && item.Num > math.MaxInt64/item.UnitPrice {
+		return nil, fmt.Errorf("amount overflow: price=%d num=%d", item.UnitPrice, item.Num)
+	}
+	cfg := pricingConfig()
+
+	q := &Quote{Item: item, Subtotal: item.UnitPrice * item.Num}
+	amount := q.Subtotal
+
+	// 满减
+	if p, ok := matchPromotion(cfg.Promotions, amount); ok {
+		q.PromotionName = p.Name
+		q.PromotionDiscount = min(p.Discount, amount)
+		amount -= q.PromotionDiscount
+	}
+
+	// 优惠券
+	if len(couponCode) > 0 {
+		c, err := coupons.GetCoupon(ctx, item.UserId, couponCode)
+		if err != nil {
+			return nil, err
+		}
+		d, err := c.discount(amount)
+		if err != nil {
+			return nil, err
+		}
+		q.CouponCode = c.Code
+		q.CouponDiscount = d
+		amount -= d
+	}
+
+	// 运费
+	if cfg.FreeShippingThreshold <= 0 || amount < cfg.FreeShippingThreshold {
+		q.ShippingFee = cfg.ShippingFee
+	}
+	q.PayAmount = amount + q.ShippingFee
+	return q, nil
+}
+
+// pricingConfig 返回当前的定价配置，没有配置时不打折、不收运费
+func pricingConfig() *config.PricingConfig {
+	if cfg := config.Get().PricingConfig; cfg != nil {
+		return cfg
+	}
+	return &config.PricingConfig{}
+}
+
+// matchPromotion 返回 amount 满足的门槛最高的满减活动
+func matchPromotion(promotions []config.Promotion, amount int64) (config.Promotion, bool) {
+	sorted := make([]config.Promotion, len(promotions))
+	copy(sorted, promotions)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Threshold > sorted[j].Threshold })
+	for _, p := range sorted {
+		if amount >= p.Threshold {
+			return p, true
+		}
+	}
+	return config.Promotion{}, false
+}
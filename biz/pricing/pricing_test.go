@@ -0,0 +1,98 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"order_service/config"
+	"order_service/errno"
+)
+
+// withPricing 测试期间使用 cfg 作为定价配置
+func withPricing(t *testing.T, cfg *config.PricingConfig) {
+	t.Helper()
+	prev := config.Conf
+	config.Conf = &config.SrvConfig{PricingConfig: cfg}
+	t.Cleanup(func() { config.Conf = prev })
+}
+
+func quote(t *testing.T, price, num int64, coupon string) *Quote {
+	t.Helper()
+	q, err := Calculate(context.Background(), Item{UserId: 1, GoodsId: 2, UnitPrice: price, Num: num}, coupon)
+	if err != nil {
+		t.Fatalf("Calculate(price=%d, num=%d, coupon=%q): %v", price, num, coupon, err)
+	}
+	if q.PayAmount != q.Subtotal-q.PromotionDiscount-q.CouponDiscount+q.ShippingFee {
+		t.Fatalf("pay amount %d does not add up: %+v", q.PayAmount, *q)
+	}
+	return q
+}
+
+func TestCalculatePromotion(t *testing.T) {
+	withPricing(t, &config.PricingConfig{Promotions: []config.Promotion{
+		{Name: "满200减30", Threshold: 20000, Discount: 3000},
+		{Name: "满100减10", Threshold: 10000, Discount: 1000},
+	}})
+
+	if q := quote(t, 9999, 1, ""); q.PromotionName != "" || q.PayAmount != 9999 {
+		t.Errorf("below every threshold: %+v", *q)
+	}
+	// 同时满足多个门槛时只用门槛最高的一个，和配置的顺序无关
+	if q := quote(t, 12500, 2, ""); q.PromotionName != "满200减30" || q.PayAmount != 22000 {
+		t.Errorf("highest threshold should win: %+v", *q)
+	}
+}
+
+// 优惠券的门槛和折扣都按满减后的金额计算
+func TestCalculateCouponAfterPromotion(t *testing.T) {
+	withPricing(t, &config.PricingConfig{
+		Promotions: []config.Promotion{{Name: "满200减30", Threshold: 20000, Discount: 3000}},
+		Coupons: []config.CouponConfig{
+			{Code: "OFF10", Type: CouponTypePercent, Value: 90, MaxDiscount: 5000},
+			{Code: "OFF10MIN200", Type: CouponTypePercent, Value: 90, MinAmount: 20000},
+		},
+	})
+
+	q := quote(t, 20000, 1, "OFF10")
+	if q.PromotionDiscount != 3000 || q.CouponDiscount != 1700 || q.PayAmount != 15300 {
+		t.Errorf("percent coupon should apply to 170.00: %+v", *q)
+	}
+
+	// 小计 200.00 满足门槛，满减后 170.00 不满足
+	_, err := Calculate(context.Background(), Item{UserId: 1, UnitPrice: 20000, Num: 1}, "OFF10MIN200")
+	if !errors.Is(err, errno.ErrCouponNotApplicable) {
+		t.Errorf("coupon threshold err = %v, want ErrCouponNotApplicable", err)
+	}
+}
+
+// 包邮门槛按满减和优惠券之后的金额计算
+func TestCalculateShippingAfterDiscounts(t *testing.T) {
+	withPricing(t, &config.PricingConfig{
+		Promotions:            []config.Promotion{{Name: "满100减10", Threshold: 10000, Discount: 1000}},
+		Coupons:               []config.CouponConfig{{Code: "MINUS5", Type: CouponTypeAmount, Value: 500}},
+		ShippingFee:           800,
+		FreeShippingThreshold: 9000,
+	})
+
+	if q := quote(t, 10000, 1, ""); q.ShippingFee != 0 {
+		t.Errorf("90.00 after promotion should ship free: %+v", *q)
+	}
+	if q := quote(t, 10000, 1, "MINUS5"); q.ShippingFee != 800 || q.PayAmount != 9300 {
+		t.Errorf("85.00 after coupon should pay shipping: %+v", *q)
+	}
+}
+
+func TestCalculateRejectsInvalidItem(t *testing.T) {
+	withPricing(t, &config.PricingConfig{})
+	for _, item := range []Item{
+		{UnitPrice: 100, Num: 0},
+		{UnitPrice: -1, Num: 1},
+		{UnitPrice: math.MaxInt64 / 2, Num: 3},
+	} {
+		if _, err := Calculate(context.Background(), item, ""); err == nil {
+			t.Errorf("Calculate(%+v) should fail", item)
+		}
+	}
+}
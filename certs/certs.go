@@ -0,0 +1,159 @@
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"order_service/config"
+	"order_service/metrics"
+
+	"go.uber.org/zap"
+)
+
+// TLS 证书
+// gRPC 服务端、网关和访问 goods/stock 的客户端共用一份证书和 CA，
+// 每次握手时读取当前的证书和 CA，文件内容变化后由 StartReloader 重新加载，已经建立的连接不受影响。
+
+const _defaultReloadInterval = time.Minute
+
+// bundle 一次加载的证书、CA 和对应的文件内容
+type bundle struct {
+	cert *tls.Certificate // 没有配置 cert_file 时为 nil
+	pool *x509.CertPool   // 没有配置 ca_file 时为 nil，使用系统 CA
+
+	raw [][]byte // 证书、私钥、CA 文件的内容，用于判断文件是否变化
+}
+
+var (
+	cfg     *config.TLSConfig
+	current atomic.Pointer[bundle]
+)
+
+// Init 加载证书，服务端和客户端都没有开启 TLS 时不加载
+func Init(c *config.TLSConfig, clientTLS bool) error {
+	if c == nil || (!c.Enable && !clientTLS) {
+		return nil
+	}
+	b, err := load(c)
+	if err != nil {
+		return err
+	}
+	cfg = c
+	store(b)
+	return nil
+}
+
+// Enabled gRPC 服务端是否开启了 TLS
+func Enabled() bool {
+	return cfg != nil && cfg.Enable
+}
+
+// StartReloader 定期检查证书文件，内容变化后重新加载，加载失败时继续使用原来的证书
+func StartReloader(ctx context.Context) {
+	if cfg == nil {
+		return
+	}
+	interval := cfg.ReloadInterval
+	if interval <= 0 {
+		interval = _defaultReloadInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reload()
+		}
+	}
+}
+
+// reload 文件内容有变化时重新加载
+func reload() {
+	old := current.Load()
+	raw, err := readFiles(cfg)
+	if err == nil && equal(raw, old.raw) {
+		return
+	}
+	b, err := load(cfg)
+	if err != nil {
+		metrics.TLSReloads.WithLabelValues("error").Inc()
+		zap.L().Error("reload tls certificate failed", zap.Error(err))
+		return
+	}
+	store(b)
+	metrics.TLSReloads.WithLabelValues("success").Inc()
+	zap.L().Info("tls certificate reloaded", zap.String("cert_file", cfg.CertFile), zap.String("ca_file", cfg.CAFile))
+}
+
+func store(b *bundle) {
+	current.Store(b)
+	if b.cert != nil && b.cert.Leaf != nil {
+		metrics.TLSCertExpiry.Set(float64(b.cert.Leaf.NotAfter.Unix()))
+	}
+}
+
+// load 读取并解析证书、私钥和 CA
+func load(c *config.TLSConfig) (*bundle, error) {
+	raw, err := readFiles(c)
+	if err != nil {
+		return nil, err
+	}
+	b := &bundle{raw: raw}
+	if len(c.CertFile) > 0 {
+		cert, err := tls.X509KeyPair(raw[0], raw[1])
+		if err != nil {
+			return nil, fmt.Errorf("load tls.cert_file/key_file failed: %w", err)
+		}
+		if cert.Leaf == nil {
+			cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+			if err != nil {
+				return nil, fmt.Errorf("parse tls.cert_file failed: %w", err)
+			}
+		}
+		b.cert = &cert
+	}
+	if len(c.CAFile) > 0 {
+		b.pool = x509.NewCertPool()
+		if !b.pool.AppendCertsFromPEM(raw[2]) {
+			return nil, errors.New("no certificate found in tls.ca_file")
+		}
+	}
+	return b, nil
+}
+
+// readFiles 读取证书、私钥、CA 文件的内容，没有配置的文件为 nil
+func readFiles(c *config.TLSConfig) ([][]byte, error) {
+	raw := make([][]byte, 3)
+	for i, name := range []string{c.CertFile, c.KeyFile, c.CAFile} {
+		if len(name) == 0 {
+			continue
+		}
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		raw[i] = b
+	}
+	return raw, nil
+}
+
+func equal(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,129 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// ServerCredentials gRPC 服务端的传输凭证，没有开启 TLS 时为明文
+// 开启 client_auth 时校验调用方提供的客户端证书，没有提供证书的请求由 UnaryServerInterceptor 拒绝，
+// 这样 Consul 不需要客户端证书也能做健康检查
+func ServerCredentials() credentials.TransportCredentials {
+	if !Enabled() {
+		return insecure.NewCredentials()
+	}
+	return credentials.NewTLS(serverConfig(tls.VerifyClientCertIfGiven))
+}
+
+// GatewayTLSConfig HTTP 网关的 TLS 配置，没有开启 TLS 时返回 nil
+// 网关使用本服务的证书转发到 gRPC 服务，开启 client_auth 时 HTTP 请求必须提供客户端证书，否则可以绕过 mTLS
+func GatewayTLSConfig() *tls.Config {
+	if !Enabled() {
+		return nil
+	}
+	return serverConfig(tls.RequireAndVerifyClientCert)
+}
+
+// serverConfig 每次握手使用最新加载的证书和 CA，开启 client_auth 时按 clientAuth 校验客户端证书
+func serverConfig(clientAuth tls.ClientAuthType) *tls.Config {
+	getConfig := func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		b := current.Load()
+		c := &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{*b.cert},
+		}
+		if cfg.ClientAuth {
+			c.ClientAuth = clientAuth
+			c.ClientCAs = b.pool
+		}
+		return c, nil
+	}
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: getConfig,
+		// http.Server.ServeTLS 要求配置了证书，实际使用 GetConfigForClient 返回的证书
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return current.Load().cert, nil
+		},
+	}
+}
+
+// ClientCredentials 访问其他服务的传输凭证，enable 为 false 时为明文
+// 使用最新加载的 CA 校验服务端证书中的 serverName，服务端要求时提供本服务的证书
+func ClientCredentials(enable bool, serverName string) credentials.TransportCredentials {
+	if !enable {
+		return insecure.NewCredentials()
+	}
+	return credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		// CA 会热加载，不能使用握手时固定的 RootCAs，改为在 VerifyConnection 中校验
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return verifyServer(cs, serverName)
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if b := current.Load(); b != nil && b.cert != nil {
+				return b.cert, nil
+			}
+			return &tls.Certificate{}, nil
+		},
+	})
+}
+
+// verifyServer 使用当前的 CA 校验服务端证书
+func verifyServer(cs tls.ConnectionState, serverName string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server did not provide a certificate")
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       serverName,
+		Intermediates: x509.NewCertPool(),
+	}
+	if b := current.Load(); b != nil {
+		opts.Roots = b.pool
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// UnaryServerInterceptor 开启 client_auth 时拒绝没有提供客户端证书的请求，健康检查除外
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !Enabled() || !cfg.ClientAuth || isHealthCheck(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		if !hasClientCert(ctx) {
+			return nil, status.Error(codes.Unauthenticated, "需要客户端证书")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// hasClientCert 调用方是否提供了校验通过的客户端证书
+func hasClientCert(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	return ok && len(info.State.VerifiedChains) > 0
+}
+
+func isHealthCheck(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+grpc_health_v1.Health_ServiceDesc.ServiceName+"/")
+}
@@ -2,12 +2,16 @@ package config
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
 )
 
-// Conf 定义全局的变量
+// Conf 启动时加载的配置
+// 配置热加载不会修改 Conf 指向的内容，运行期间需要读取最新配置的地方使用 Get()，
+// 需要在配置变化时做处理的组件使用 Subscribe()，见 reload.go
 var Conf = new(SrvConfig)
 
 // viper.GetXxx()读取的方式
@@ -27,23 +31,106 @@ type SrvConfig struct {
 	Port int    `mapstructure:"port"`
 
 	*LogConfig      `mapstructure:"log"`
+	*StorageConfig  `mapstructure:"storage"`
 	*MySQLConfig    `mapstructure:"mysql"`
+	*ShardingConfig `mapstructure:"sharding"`
 	*RedisConfig    `mapstructure:"redis"`
+	*CacheConfig    `mapstructure:"cache"`
+	*LockConfig     `mapstructure:"lock"`
+	*LedgerConfig   `mapstructure:"ledger"`
 	*ConsulConfig   `mapstructure:"consul"`
 	*RocketMqConfig `mapstructure:"rocketmq"`
 
 	*GoodsService `mapstructure:"goods_service"`
 	*StockService `mapstructure:"stock_service"`
+
+	*HealthConfig  `mapstructure:"health"`
+	*GatewayConfig `mapstructure:"gateway"`
+	*MetricsConfig `mapstructure:"metrics"`
+	*TraceConfig   `mapstructure:"trace"`
+
+	*StockReconcileConfig `mapstructure:"stock_reconcile"`
+	*ArchiveConfig        `mapstructure:"archive"`
+	*PricingConfig        `mapstructure:"pricing"`
+	*AuthConfig           `mapstructure:"auth"`
+	*TLSConfig            `mapstructure:"tls"`
+	*RateLimitConfig      `mapstructure:"rate_limit"`
 }
 
 type GoodsService struct {
-	Name string `mapstructure:"name"`
+	Name       string `mapstructure:"name"`
+	TLS        bool   `mapstructure:"tls"`         // 是否使用 TLS 连接，客户端证书使用 tls 中配置的证书
+	ServerName string `mapstructure:"server_name"` // 校验服务端证书时使用的名称，默认为 name
 }
 
 type StockService struct {
-	Name string `mapstructure:"name"`
+	Name       string `mapstructure:"name"`
+	TLS        bool   `mapstructure:"tls"`         // 是否使用 TLS 连接，客户端证书使用 tls 中配置的证书
+	ServerName string `mapstructure:"server_name"` // 校验服务端证书时使用的名称，默认为 name
+}
+
+// StorageConfig 存储后端配置
+type StorageConfig struct {
+	Driver     string `mapstructure:"driver"`      // mysql（默认）或 sqlite
+	SQLitePath string `mapstructure:"sqlite_path"` // driver 为 sqlite 时的数据库文件，:memory: 表示内存数据库
+}
+
+// DriverName 存储后端，未配置时使用 mysql
+func (c *StorageConfig) DriverName() string {
+	if c == nil || len(c.Driver) == 0 {
+		return "mysql"
+	}
+	return c.Driver
+}
+
+// ShardingConfig 订单分库分表配置
+// 订单和订单明细按用户ID分到多个库中，每个库中再分成多张表，订单号的低位保存分片号（基因），按订单号查询时可以直接定位库和表。
+// 库和表的数量都必须是 2 的幂，上线后不能修改；优惠券等不分片的表只使用第一个库。
+type ShardingConfig struct {
+	Shards []ShardConfig `mapstructure:"shards"` // 为空时不分库，只使用 mysql（或 storage.sqlite_path）配置的库
+	Tables int           `mapstructure:"tables"` // 每个库中订单表和订单明细表的分表数量，0 或 1 表示不分表
+}
+
+// ShardConfig 一个分片库
+type ShardConfig struct {
+	Host string `mapstructure:"host"`   // 为空时使用 mysql.host
+	Port int    `mapstructure:"port"`   // 为空时使用 mysql.port
+	DB   string `mapstructure:"dbname"` // 数据库名，storage.driver 为 sqlite 时是数据库文件
+
+	Replicas []ReplicaConfig `mapstructure:"replicas"` // 分片的只读从库，不使用 mysql.replicas
+}
+
+// DBBits 订单号中保存分库序号的位数，不分库时为 0
+func (c *ShardingConfig) DBBits() uint8 {
+	if c == nil {
+		return 0
+	}
+	return log2(len(c.Shards))
+}
+
+// TableBits 订单号中保存分表序号的位数，在分库序号之上，不分表时为 0
+func (c *ShardingConfig) TableBits() uint8 {
+	if c == nil {
+		return 0
+	}
+	return log2(c.Tables)
 }
 
+// GeneBits 订单号中保存分片号（分库序号和分表序号）的位数，不分库也不分表时为 0
+func (c *ShardingConfig) GeneBits() uint8 {
+	return c.DBBits() + c.TableBits()
+}
+
+// log2 n 向上取整的以 2 为底的对数，n <= 1 时为 0
+func log2(n int) uint8 {
+	var bits uint8
+	for 1<<bits < n {
+		bits++
+	}
+	return bits
+}
+
+// MySQLConfig MySQL 配置，host/port 为主库
 type MySQLConfig struct {
 	Host         string `mapstructure:"host"`
 	User         string `mapstructure:"user"`
@@ -52,6 +139,17 @@ type MySQLConfig struct {
 	Port         int    `mapstructure:"port"`
 	MaxOpenConns int    `mapstructure:"max_open_conns"`
 	MaxIdleConns int    `mapstructure:"max_idle_conns"`
+
+	// 只读从库，订单列表和订单详情的查询读从库，为空时都读主库
+	Replicas             []ReplicaConfig `mapstructure:"replicas"`
+	MaxReplicaLag        time.Duration   `mapstructure:"max_replica_lag"`        // 从库复制延迟超过这个时间时读主库，默认 1s
+	ReplicaCheckInterval time.Duration   `mapstructure:"replica_check_interval"` // 检查从库复制延迟的间隔，默认 5s
+}
+
+// ReplicaConfig 一个只读从库，账号、库名和连接池大小与主库相同
+type ReplicaConfig struct {
+	Host string `mapstructure:"host"`
+	Port int    `mapstructure:"port"` // 为空时使用主库的端口
 }
 
 type RedisConfig struct {
@@ -64,33 +162,201 @@ type RedisConfig struct {
 }
 
 type LogConfig struct {
-	Level      string `mapstructure:"level"`
-	Filename   string `mapstructure:"filename"`
-	MaxSize    int    `mapstructure:"max_size"`
-	MaxAge     int    `mapstructure:"max_age"`
-	MaxBackups int    `mapstructure:"max_backups"`
+	Level         string `mapstructure:"level"`
+	Filename      string `mapstructure:"filename"`
+	ErrorFilename string `mapstructure:"error_filename"` // error 及以上级别的日志单独记录一份，默认为 xxx.err.log
+	MaxSize       int    `mapstructure:"max_size"`
+	MaxAge        int    `mapstructure:"max_age"`
+	MaxBackups    int    `mapstructure:"max_backups"`
+
+	Packages map[string]string `mapstructure:"packages"` // 按包覆盖日志级别，例如 biz/order: debug
 }
 
 type ConsulConfig struct {
-	Addr string `mapstructure:"addr"`
+	Addr      string `mapstructure:"addr"`
+	ConfigKey string `mapstructure:"config_key"` // Consul KV 中配置的 key（YAML 格式），为空时不使用远程配置
+	Watch     bool   `mapstructure:"watch"`      // 是否监听 Consul KV 中配置的变化
+}
+
+// HealthConfig 依赖健康检查配置
+type HealthConfig struct {
+	Interval time.Duration `mapstructure:"interval"` // 探测间隔
+	Timeout  time.Duration `mapstructure:"timeout"`  // 单次探测超时时间
+}
+
+// CacheConfig 订单查询缓存配置
+type CacheConfig struct {
+	Enable      bool          `mapstructure:"enable"`
+	OrderTTL    time.Duration `mapstructure:"order_ttl"`     // 订单和订单明细的缓存时间
+	ListTTL     time.Duration `mapstructure:"list_ttl"`      // 订单列表的缓存时间
+	NotFoundTTL time.Duration `mapstructure:"not_found_ttl"` // 不存在的订单的缓存时间，防止缓存穿透
+}
+
+// LockConfig 订单分布式锁配置
+type LockConfig struct {
+	Expiry     time.Duration `mapstructure:"expiry"`      // 锁的过期时间，持有期间每 expiry/2 续期一次
+	Tries      int           `mapstructure:"tries"`       // 加锁的最大尝试次数
+	RetryDelay time.Duration `mapstructure:"retry_delay"` // 加锁失败后的重试间隔
+}
+
+// LedgerConfig 库存补偿台账对账配置
+type LedgerConfig struct {
+	ReconcileInterval time.Duration `mapstructure:"reconcile_interval"` // 对账间隔，0 表示不启动对账任务
+	Window            time.Duration `mapstructure:"window"`             // 只对账这段时间内创建的记录
+	Grace             time.Duration `mapstructure:"grace"`              // 最近这段时间内的记录可能还在处理中，不参与对账
 }
 
+// StockReconcileConfig 订单-库存对账配置
+type StockReconcileConfig struct {
+	Interval     time.Duration `mapstructure:"interval"`      // 对账间隔，0 表示不启动定时对账
+	Window       time.Duration `mapstructure:"window"`        // 对账最近这段时间内的扣减记录
+	OrphanAge    time.Duration `mapstructure:"orphan_age"`    // 扣减后超过这个时间仍然没有订单的，视为孤儿扣减
+	AutoRollback bool          `mapstructure:"auto_rollback"` // 是否自动回滚孤儿扣减，false 时只报告
+	PageSize     int32         `mapstructure:"page_size"`     // 每次从 stock_service 拉取的记录数
+}
+
+// ArchiveConfig 冷订单归档配置
+// 已完成、已取消、支付超时并且创建超过 min_age 的订单分批移动到归档表
+type ArchiveConfig struct {
+	Interval      time.Duration `mapstructure:"interval"`       // 归档任务的执行间隔，0 表示不启动
+	MinAge        time.Duration `mapstructure:"min_age"`        // 创建超过这个时间的订单才归档
+	BatchSize     int           `mapstructure:"batch_size"`     // 每批（每个事务）归档的订单数
+	BatchInterval time.Duration `mapstructure:"batch_interval"` // 两批之间的间隔，降低对数据库的压力
+}
+
+// PricingConfig 定价配置，金额单位都是分
+type PricingConfig struct {
+	Promotions            []Promotion    `mapstructure:"promotions"`              // 满减活动
+	ShippingFee           int64          `mapstructure:"shipping_fee"`            // 运费
+	FreeShippingThreshold int64          `mapstructure:"free_shipping_threshold"` // 包邮门槛，0 表示不包邮
+	Coupons               []CouponConfig `mapstructure:"coupons"`                 // 通用优惠券
+}
+
+// Promotion 满减活动：满 threshold 减 discount
+type Promotion struct {
+	Name      string `mapstructure:"name"`
+	Threshold int64  `mapstructure:"threshold"`
+	Discount  int64  `mapstructure:"discount"`
+}
+
+// CouponConfig 通用优惠券
+type CouponConfig struct {
+	Code        string `mapstructure:"code"`
+	Type        string `mapstructure:"type"`         // amount 立减，percent 折扣
+	Value       int64  `mapstructure:"value"`        // 立减金额，或者折扣百分比（90 表示 9 折）
+	MinAmount   int64  `mapstructure:"min_amount"`   // 使用门槛
+	MaxDiscount int64  `mapstructure:"max_discount"` // 折扣券最高减免，0 表示不限制
+}
+
+// AuthConfig 接口鉴权配置
+// 开启后除健康检查外的接口都要在 metadata authorization 中携带 "Bearer {JWT}"，
+// HS256/384/512 使用 hmac_secret 校验，RS256/384/512 使用 rsa_public_key 校验，两者可以同时配置
+type AuthConfig struct {
+	Enable       bool          `mapstructure:"enable"`
+	HMACSecret   string        `mapstructure:"hmac_secret"`    // HMAC 密钥，至少 32 字节
+	RSAPublicKey string        `mapstructure:"rsa_public_key"` // RSA 公钥（PEM 格式）
+	Issuer       string        `mapstructure:"issuer"`         // 要求的 iss，为空时不校验
+	Audience     string        `mapstructure:"audience"`       // 要求的 aud，为空时不校验
+	Leeway       time.Duration `mapstructure:"leeway"`         // 校验 exp/nbf 时允许的时钟误差
+	AdminRole    string        `mapstructure:"admin_role"`     // roles 中包含该角色的是运维人员，可以调用 Admin 接口，默认 admin
+}
+
+// TLSConfig gRPC TLS 配置
+// gRPC 服务端和访问 goods/stock 的客户端使用同一份证书，开启 mTLS 时证书需要同时包含 serverAuth 和 clientAuth 用途。
+// 证书、私钥和 CA 文件每隔 reload_interval 检查一次，内容变化后重新加载，新建立的连接使用新证书。
+type TLSConfig struct {
+	Enable         bool          `mapstructure:"enable"`          // gRPC 服务端是否开启 TLS
+	CertFile       string        `mapstructure:"cert_file"`       // 证书（PEM），可以包含中间证书
+	KeyFile        string        `mapstructure:"key_file"`        // 私钥（PEM）
+	CAFile         string        `mapstructure:"ca_file"`         // 校验对端证书的 CA，为空时使用系统 CA
+	ClientAuth     bool          `mapstructure:"client_auth"`     // 是否要求调用方提供 ca_file 签发的客户端证书（mTLS），健康检查除外
+	ServerName     string        `mapstructure:"server_name"`     // 本服务证书中的名称，网关和 Consul 健康检查用于校验证书，默认为 name
+	ReloadInterval time.Duration `mapstructure:"reload_interval"` // 检查证书文件变化的间隔，默认 1m
+
+	// Consul 健康检查不校验本服务的证书，Consul agent 没有配置签发本服务证书的 CA 时使用
+	HealthCheckSkipVerify bool `mapstructure:"health_check_skip_verify"`
+}
+
+// RateLimitConfig 接口限流配置
+// 每条规则对应一个接口，可以同时配置三种限制，任一种超过限制时返回 ResourceExhausted：
+//   - rate/burst：本实例的令牌桶，限制接口的总请求量
+//   - user_limit/user_window：同一个用户在 user_window 内最多请求 user_limit 次，在 Redis 中按滑动窗口计数，所有实例共享
+//   - goods_limit/goods_window：同一个商品在 goods_window 内最多请求 goods_limit 次，只对请求中有 goods_id 的接口生效
+//
+// 配置修改后立即生效；Redis 不可用时不做用户和商品维度的限制
+type RateLimitConfig struct {
+	Enable bool            `mapstructure:"enable"`
+	Rules  []RateLimitRule `mapstructure:"rules"`
+}
+
+// RateLimitRule 一个接口的限流规则，各项限制为 0 时不限制
+type RateLimitRule struct {
+	Method      string        `mapstructure:"method"`       // 接口名，例如 CreateOrder，或者完整的 /order.Order/CreateOrder
+	Rate        float64       `mapstructure:"rate"`         // 令牌桶每秒生成的令牌数
+	Burst       int           `mapstructure:"burst"`        // 令牌桶容量，默认为 rate 向上取整
+	UserLimit   int           `mapstructure:"user_limit"`   // 每个用户在 user_window 内的最大请求数
+	UserWindow  time.Duration `mapstructure:"user_window"`  // 用户维度的滑动窗口
+	GoodsLimit  int           `mapstructure:"goods_limit"`  // 每个商品在 goods_window 内的最大请求数
+	GoodsWindow time.Duration `mapstructure:"goods_window"` // 商品维度的滑动窗口
+}
+
+// MethodName 规则对应的方法名，/order.Order/CreateOrder 和 CreateOrder 都返回 CreateOrder
+func (r RateLimitRule) MethodName() string {
+	return r.Method[strings.LastIndex(r.Method, "/")+1:]
+}
+
+// GatewayConfig HTTP/JSON 网关配置
+type GatewayConfig struct {
+	Port int `mapstructure:"port"` // 网关监听端口，0 表示不启动
+}
+
+// MetricsConfig Prometheus 指标配置
+type MetricsConfig struct {
+	Port int `mapstructure:"port"` // /metrics 监听端口，0 表示不启动
+}
+
+// TraceConfig OpenTelemetry 链路追踪配置
+type TraceConfig struct {
+	Enable      bool    `mapstructure:"enable"`
+	Exporter    string  `mapstructure:"exporter"`     // stdout 或 otlp
+	Endpoint    string  `mapstructure:"endpoint"`     // otlp collector 地址，例如 127.0.0.1:4317
+	Insecure    bool    `mapstructure:"insecure"`     // otlp 是否使用明文连接
+	SampleRatio float64 `mapstructure:"sample_ratio"` // 采样率，0~1，默认全部采样
+}
+
+// RocketMqConfig 消息队列配置，所有生产者和消费者都从这里读取
 type RocketMqConfig struct {
-	Addr    string `mapstructure:"addr"`
-	GroupId string `mapstructure:"group_id"`
-	Topic   struct {
-		PayTimeOut    string `mapstructure:"pay_timeout"`
-		StockRollback string `mapstructure:"stock_rollback"`
-		CreateOrder   string `mapstrcuture:"create_order"`
-		CreateOderSuccessfully string `mapstrcuture:"create order successfully"`
-	} `mapstructure:"topic"`
+	NameServers []string `mapstructure:"name_servers"` // NameServer 地址列表
+	GroupId     string   `mapstructure:"group_id"`     // 普通消息生产者组
+	TxGroupId   string   `mapstructure:"tx_group_id"`  // 事务消息生产者组
+	// 订单超时消息的消费者组
+	ConsumerGroupId string `mapstructure:"consumer_group_id"`
+	// 生产者发送失败的重试次数
+	ProducerRetry int `mapstructure:"producer_retry"`
+	// 消费失败的最大重试次数，超过后转入死信 topic，后续人工处理
+	MaxReconsumeTimes int32 `mapstructure:"max_reconsume_times"`
+	// 订单超时延迟消息的延迟级别（1-18），对应 broker 的 messageDelayLevel 配置
+	PayTimeoutDelayLevel int `mapstructure:"pay_timeout_delay_level"`
+
+	Topic RocketMqTopic `mapstructure:"topic"`
+}
+
+// RocketMqTopic 订单服务用到的所有 topic
+type RocketMqTopic struct {
+	CreateOrder             string `mapstructure:"create_order"`         // 创建订单的事务消息
+	CreateOrderSuccessfully string `mapstructure:"create_order_success"` // 订单创建成功
+	PayTimeOut              string `mapstructure:"pay_timeout"`          // 订单支付超时（延迟消息）
+	StockRollback           string `mapstructure:"stock_rollback"`       // 库存回滚
+	DeadLetter              string `mapstructure:"dead_letter"`          // 超过重试次数的消息
 }
 
 // Init 整个服务配置文件初始化的方法
+// 配置来源和优先级见 source.go
 func Init(filePath string) (err error) {
 	// 方式1：直接指定配置文件路径（相对路径或者绝对路径）
 	// 相对路径：相对执行的可执行文件的相对路径
 	// viper.SetConfigFile("./conf/config.yaml")
+	configFile = filePath
 	viper.SetConfigFile(filePath)
 
 	err = viper.ReadInConfig() // 读取配置信息
@@ -99,19 +365,25 @@ func Init(filePath string) (err error) {
 		fmt.Printf("viper.ReadInConfig failed, err:%v\n", err)
 		return
 	}
-	// 如果使用的是 viper.GetXxx()方式使用配置的话，就无须下面的操作
 
-	// 把读取到的配置信息反序列化到 Conf 变量中
-	if err := viper.Unmarshal(Conf); err != nil {
-		fmt.Printf("viper.Unmarshal failed, err:%v\n", err)
+	// 读取 Consul KV 中的配置
+	if err = initRemote(); err != nil {
+		fmt.Printf("init remote config failed, err:%v\n", err)
+		return
+	}
+
+	// 合并各个配置源并反序列化到 Conf 变量中，同时校验配置是否合法
+	Conf, err = load()
+	if err != nil {
+		fmt.Printf("load config failed, err:%v\n", err)
+		return
 	}
+	current.Store(Conf)
 
 	viper.WatchConfig() // 配置文件监听
 	viper.OnConfigChange(func(in fsnotify.Event) {
 		fmt.Println("配置文件修改了...")
-		if err := viper.Unmarshal(Conf); err != nil {
-			fmt.Printf("viper.Unmarshal failed, err:%v\n", err)
-		}
+		reload()
 	})
 	return
 }
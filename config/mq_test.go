@@ -0,0 +1,42 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// 示例配置中的每个 topic 都要能反序列化出来（之前 mapstructure 标签拼错导致 topic 始终为空）
+func TestRocketMqTopicsDecoded(t *testing.T) {
+	useConfigFile(t)
+	c, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := c.RocketMqConfig.Topic
+	want := RocketMqTopic{
+		CreateOrder:             "xx_create_order",
+		CreateOrderSuccessfully: "xx_create_order_success",
+		PayTimeOut:              "xx_order_timeout",
+		StockRollback:           "xx_stock_rollback",
+		DeadLetter:              "xx_order_dead_letter",
+	}
+	if got != want {
+		t.Errorf("topic = %+v, want %+v", got, want)
+	}
+}
+
+// 消息配置缺失或者有误时启动失败
+func TestRocketMqConfigRejected(t *testing.T) {
+	for edit, wantErr := range map[[2]string]string{
+		{"dead_letter: xx_order_dead_letter", `dead_letter: ""`}:          "rocketmq.topic.dead_letter is required",
+		{"pay_timeout: xx_order_timeout", "pay_timeout: xx_create_order"}: "are both \"xx_create_order\"",
+		{"- 127.0.0.1:9876", "- 127.0.0.1"}:                               "invalid rocketmq.name_servers",
+		{"pay_timeout_delay_level: 3", "pay_timeout_delay_level: 19"}:     "invalid rocketmq.pay_timeout_delay_level",
+		{"tx_group_id: order_srv_tx", `tx_group_id: ""`}:                  "rocketmq.tx_group_id is required",
+	} {
+		useConfigFile(t, edit[0], edit[1])
+		if _, err := load(); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("replace %q: err = %v, want %q", edit[0], err, wantErr)
+		}
+	}
+}
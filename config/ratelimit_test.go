@@ -0,0 +1,15 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// CreateOrder 和 /order.Order/CreateOrder 是同一个接口，不能配置两条规则
+func TestRateLimitDuplicateMethod(t *testing.T) {
+	useConfigFile(t, "method: QuoteOrder", "method: /order.Order/CreateOrder")
+	_, err := load()
+	if err == nil || !strings.Contains(err.Error(), "duplicate rate_limit.rules[1].method") {
+		t.Fatalf("err = %v, want duplicate method", err)
+	}
+}
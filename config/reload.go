@@ -0,0 +1,78 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// 配置热加载
+// 配置文件或 Consul KV 修改后反序列化到一个新的 SrvConfig 中，校验通过后整体替换，
+// 读取方通过 Get() 拿到的始终是一份完整且校验过的配置，不会读到修改了一半的数据。
+// 组件通过 Subscribe 订阅配置变化，任一订阅者应用失败时回滚到上一份配置。
+
+// Subscriber 配置变化的回调，old 为变化前的配置，cur 为新配置
+// 返回错误表示新配置无法应用，会触发回滚
+type Subscriber func(old, cur *SrvConfig) error
+
+type subscriber struct {
+	name string
+	fn   Subscriber
+}
+
+var (
+	current atomic.Pointer[SrvConfig] // 当前生效的配置
+
+	reloadMu    sync.Mutex // 保证同一时间只有一次热加载
+	subscribers []subscriber
+)
+
+// Get 返回当前生效的配置，返回值只读，不要修改
+func Get() *SrvConfig {
+	if c := current.Load(); c != nil {
+		return c
+	}
+	return Conf
+}
+
+// Subscribe 订阅配置变化，name 用于日志
+func Subscribe(name string, fn Subscriber) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	subscribers = append(subscribers, subscriber{name: name, fn: fn})
+}
+
+// reload 重新加载配置文件
+// 反序列化或校验失败时保留上一份配置；订阅者应用失败时，
+// 已经应用了新配置的订阅者按相反顺序回滚到上一份配置
+func reload() {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	next, err := load()
+	if err != nil {
+		zap.L().Error("reload config failed, keep last good config", zap.Error(err))
+		return
+	}
+
+	prev := Get()
+	current.Store(next)
+	applied := make([]subscriber, 0, len(subscribers))
+	for _, s := range subscribers {
+		if err := s.fn(prev, next); err != nil {
+			zap.L().Error("apply config failed, rollback to last good config",
+				zap.String("subscriber", s.name), zap.Error(err))
+			current.Store(prev)
+			for i := len(applied) - 1; i >= 0; i-- {
+				if err := applied[i].fn(next, prev); err != nil {
+					zap.L().Error("rollback config failed",
+						zap.String("subscriber", applied[i].name), zap.Error(err))
+				}
+			}
+			return
+		}
+		applied = append(applied, s)
+	}
+	zap.L().Info("config reloaded", zap.Int("subscribers", len(applied)))
+}
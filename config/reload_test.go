@@ -0,0 +1,111 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useConfigFile 把仓库中的示例配置按 oldnew（旧内容、新内容成对出现）修改后写入临时文件，作为 load 读取的配置文件
+// 同一个测试中再次调用相当于修改了配置文件
+func useConfigFile(t *testing.T, oldnew ...string) {
+	t.Helper()
+	b, err := os.ReadFile("../conf/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := strings.ReplaceAll(string(b), "\r\n", "\n")
+	for i := 0; i+1 < len(oldnew); i += 2 {
+		if !strings.Contains(s, oldnew[i]) {
+			t.Fatalf("sample config has no %q", oldnew[i])
+		}
+		s = strings.Replace(s, oldnew[i], oldnew[i+1], 1)
+	}
+
+	prev := configFile
+	configFile = filepath.Join(t.TempDir(), "config.yaml")
+	t.Cleanup(func() { configFile = prev })
+	if err := os.WriteFile(configFile, []byte(s), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startWith 以 oldnew 修改后的示例配置作为当前配置，并清空订阅者
+func startWith(t *testing.T, oldnew ...string) *SrvConfig {
+	t.Helper()
+	useConfigFile(t, oldnew...)
+	c, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prevCur, prevSubs := current.Load(), subscribers
+	current.Store(c)
+	subscribers = nil
+	t.Cleanup(func() {
+		current.Store(prevCur)
+		subscribers = prevSubs
+	})
+	return c
+}
+
+func TestReloadKeepsLastGoodConfig(t *testing.T) {
+	first := startWith(t)
+	var calls int
+	Subscribe("test", func(old, cur *SrvConfig) error {
+		calls++
+		if old != first || cur.Port != 9000 {
+			t.Errorf("subscriber got old.port=%d cur.port=%d", old.Port, cur.Port)
+		}
+		return nil
+	})
+
+	// 校验失败，保留原来的配置，不通知订阅者
+	useConfigFile(t, "port: 8389", "port: 70000")
+	reload()
+	if Get() != first || calls != 0 {
+		t.Fatalf("invalid config applied: port=%d calls=%d", Get().Port, calls)
+	}
+
+	useConfigFile(t, "port: 8389", "port: 9000")
+	reload()
+	if Get().Port != 9000 || calls != 1 {
+		t.Fatalf("valid config not applied: port=%d calls=%d", Get().Port, calls)
+	}
+	// 旧的快照不会被修改，正在使用它的读取方不受影响
+	if first.Port != 8389 {
+		t.Errorf("previous snapshot modified: port=%d", first.Port)
+	}
+}
+
+// 订阅者应用失败时，已经应用了新配置的订阅者按相反顺序回滚
+func TestReloadRollsBackSubscribers(t *testing.T) {
+	first := startWith(t)
+	var events []string
+	for _, name := range []string{"mysql", "redis"} {
+		name := name
+		Subscribe(name, func(old, cur *SrvConfig) error {
+			events = append(events, name+":"+cur.Version)
+			return nil
+		})
+	}
+	Subscribe("broken", func(old, cur *SrvConfig) error {
+		if cur.Version == "v2" {
+			return errors.New("cannot apply")
+		}
+		return nil
+	})
+
+	useConfigFile(t, `version: "v0.0.1"`, `version: "v2"`)
+	reload()
+
+	if Get() != first {
+		t.Fatalf("current config = %s, want rollback to %s", Get().Version, first.Version)
+	}
+	want := []string{"mysql:v2", "redis:v2", "redis:v0.0.1", "mysql:v0.0.1"}
+	if strings.Join(events, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// 分层配置
+// 优先级从低到高：配置文件 < Consul KV < 环境变量（ORDER_ 前缀）
+// 例如 ORDER_MYSQL_PASSWORD 覆盖 mysql.password，ORDER_ROCKETMQ_NAME_SERVERS 覆盖 rocketmq.name_servers（多个地址用逗号分隔）。
+// 注意：环境变量只能覆盖配置文件或 Consul KV 中已经出现过的配置项。
+//
+// 字符串配置项的值以 file:// 开头时，表示从文件中读取（例如 k8s/docker 挂载的 secret），
+// 例如 password: "file:///run/secrets/mysql_password"，密码就不需要写在仓库里。
+
+const (
+	envPrefix    = "ORDER"
+	secretPrefix = "file://"
+)
+
+var (
+	configFile string // 配置文件路径
+
+	remoteMu   sync.RWMutex
+	remoteData []byte // 最近一次从 Consul KV 读取到的配置内容
+)
+
+// load 按优先级合并各个配置源，反序列化并校验
+// 每次都使用新的 viper 实例，避免和配置文件监听的 goroutine 共享状态
+func load() (*SrvConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(configFile)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read config file failed: %w", err)
+	}
+
+	remoteMu.RLock()
+	data := remoteData
+	remoteMu.RUnlock()
+	if len(data) > 0 {
+		if err := v.MergeConfig(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("merge consul config failed: %w", err)
+		}
+	}
+
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	c := new(SrvConfig)
+	if err := v.Unmarshal(c); err != nil {
+		return nil, fmt.Errorf("unmarshal config failed: %w", err)
+	}
+	if err := resolveSecrets(reflect.ValueOf(c)); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// resolveSecrets 递归处理配置中所有 file:// 开头的字符串，替换为文件内容
+func resolveSecrets(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return resolveSecrets(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			if err := resolveSecrets(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			val := v.MapIndex(k)
+			if val.Kind() != reflect.String || !strings.HasPrefix(val.String(), secretPrefix) {
+				continue
+			}
+			s, err := readSecret(val.String())
+			if err != nil {
+				return err
+			}
+			v.SetMapIndex(k, reflect.ValueOf(s))
+		}
+	case reflect.String:
+		if !strings.HasPrefix(v.String(), secretPrefix) || !v.CanSet() {
+			return nil
+		}
+		s, err := readSecret(v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	}
+	return nil
+}
+
+// readSecret 读取 file:// 引用的文件内容，去掉首尾空白
+func readSecret(ref string) (string, error) {
+	path := strings.TrimPrefix(ref, secretPrefix)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret %s failed: %w", path, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// initRemote 从 Consul KV 读取配置，consul.config_key 为空时不启用
+// watch 为 true 时启动后台 goroutine 监听 key 的变化，变化后触发热加载
+func initRemote() error {
+	// 远程配置源的地址只能来自配置文件或环境变量
+	v := viper.New()
+	v.SetConfigFile(configFile)
+	if err := v.ReadInConfig(); err != nil {
+		return err
+	}
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	addr, key := v.GetString("consul.addr"), v.GetString("consul.config_key")
+	if len(addr) == 0 || len(key) == 0 {
+		return nil
+	}
+
+	cfg := api.DefaultConfig()
+	cfg.Address = addr
+	client, err := api.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	pair, meta, err := client.KV().Get(key, nil)
+	if err != nil {
+		return fmt.Errorf("get consul config %s failed: %w", key, err)
+	}
+	if pair != nil {
+		setRemoteData(pair.Value)
+	}
+
+	if v.GetBool("consul.watch") {
+		go watchRemote(client, key, meta.LastIndex)
+	}
+	return nil
+}
+
+// watchRemote 通过 consul 的阻塞查询监听配置 key 的变化
+func watchRemote(client *api.Client, key string, index uint64) {
+	for {
+		pair, meta, err := client.KV().Get(key, &api.QueryOptions{WaitIndex: index, WaitTime: 5 * time.Minute})
+		if err != nil {
+			zap.L().Warn("watch consul config failed", zap.String("key", key), zap.Error(err))
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		if meta.LastIndex == index {
+			continue // 阻塞查询超时，key 没有变化
+		}
+		if meta.LastIndex < index {
+			index = 0 // consul 的 index 被重置，重新开始
+			continue
+		}
+		index = meta.LastIndex
+
+		var data []byte
+		if pair != nil {
+			data = pair.Value
+		}
+		if !setRemoteData(data) {
+			continue
+		}
+		zap.L().Info("consul config changed", zap.String("key", key))
+		reload()
+	}
+}
+
+// setRemoteData 保存 Consul KV 中的配置内容，返回内容是否有变化
+func setRemoteData(data []byte) bool {
+	remoteMu.Lock()
+	defer remoteMu.Unlock()
+	if bytes.Equal(remoteData, data) {
+		return false
+	}
+	remoteData = data
+	return true
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 优先级：配置文件 < Consul KV < 环境变量
+func TestLoadLayers(t *testing.T) {
+	useConfigFile(t)
+	setRemoteData([]byte("port: 9100\nversion: \"from-consul\"\n"))
+	t.Cleanup(func() { setRemoteData(nil) })
+	t.Setenv("ORDER_PORT", "9200")
+	t.Setenv("ORDER_ROCKETMQ_NAME_SERVERS", "10.0.0.1:9876,10.0.0.2:9876")
+
+	c, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version != "from-consul" {
+		t.Errorf("version = %q, consul KV should override the file", c.Version)
+	}
+	if c.Port != 9200 {
+		t.Errorf("port = %d, env should override consul KV", c.Port)
+	}
+	if want := []string{"10.0.0.1:9876", "10.0.0.2:9876"}; !reflect.DeepEqual(c.RocketMqConfig.NameServers, want) {
+		t.Errorf("name_servers = %v, want %v", c.RocketMqConfig.NameServers, want)
+	}
+	if c.Name != "order_srv" {
+		t.Errorf("name = %q, keys not overridden should come from the file", c.Name)
+	}
+}
+
+func TestLoadSecretFromFile(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "mysql_password")
+	if err := os.WriteFile(secret, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// 配置文件中引用 secret 文件
+	useConfigFile(t, `password: ""`, `password: "file://`+secret+`"`)
+	c, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MySQLConfig.Password != "s3cret" {
+		t.Errorf("mysql.password = %q, want content of %s", c.MySQLConfig.Password, secret)
+	}
+
+	// 环境变量中同样可以引用 secret 文件
+	useConfigFile(t)
+	t.Setenv("ORDER_MYSQL_PASSWORD", "file://"+secret)
+	if c, err = load(); err != nil {
+		t.Fatal(err)
+	}
+	if c.MySQLConfig.Password != "s3cret" {
+		t.Errorf("env secret: mysql.password = %q", c.MySQLConfig.Password)
+	}
+
+	// secret 文件不存在时加载失败，不能把 file:// 引用本身当作密码
+	t.Setenv("ORDER_MYSQL_PASSWORD", "file://"+filepath.Join(dir, "missing"))
+	if _, err = load(); err == nil || !strings.Contains(err.Error(), "read secret") {
+		t.Errorf("missing secret: err = %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// _maxShards 最多的分库数量
+const _maxShards = 16
+
+// _maxGeneBits 订单号中分片号（分库序号和分表序号）最多占用的位数，分片号占用 snowflake 序列号的低位，
+// 分库数 × 分表数为 64 时每个节点每毫秒最多生成 64 个订单号
+const _maxGeneBits = 6
+
+// _minHMACSecretLen HMAC 密钥的最小长度，和 HS256 的输出长度相同
+const _minHMACSecretLen = 32
+
+// Validate 校验配置是否合法，启动和热加载时都会调用
+func (c *SrvConfig) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(len(c.Name) > 0, "name is required")
+	check(c.Port > 0 && c.Port < 65536, "invalid port: %d", c.Port)
+	check(c.MachineID >= 0, "invalid machine_id: %d", c.MachineID)
+
+	if c.LogConfig == nil {
+		check(false, "log is required")
+	} else {
+		var l zapcore.Level
+		check(l.UnmarshalText([]byte(c.LogConfig.Level)) == nil, "invalid log.level: %q", c.LogConfig.Level)
+		for pkg, text := range c.LogConfig.Packages {
+			check(l.UnmarshalText([]byte(text)) == nil, "invalid log.packages.%s: %q", pkg, text)
+		}
+		check(len(c.LogConfig.Filename) > 0, "log.filename is required")
+	}
+
+	switch c.StorageConfig.DriverName() {
+	case "mysql":
+	case "sqlite":
+		check(len(c.StorageConfig.SQLitePath) > 0, "storage.sqlite_path is required")
+	default:
+		check(false, "invalid storage.driver: %q", c.StorageConfig.Driver)
+	}
+
+	// 使用其他存储后端时不需要 MySQL 配置
+	if c.StorageConfig.DriverName() == "mysql" {
+		if c.MySQLConfig == nil {
+			check(false, "mysql is required")
+		} else {
+			check(len(c.MySQLConfig.Host) > 0, "mysql.host is required")
+			check(len(c.MySQLConfig.DB) > 0, "mysql.dbname is required")
+			check(c.MySQLConfig.MaxOpenConns >= 0, "invalid mysql.max_open_conns: %d", c.MySQLConfig.MaxOpenConns)
+			check(c.MySQLConfig.MaxIdleConns >= 0, "invalid mysql.max_idle_conns: %d", c.MySQLConfig.MaxIdleConns)
+			check(c.MySQLConfig.MaxOpenConns == 0 || c.MySQLConfig.MaxIdleConns <= c.MySQLConfig.MaxOpenConns,
+				"mysql.max_idle_conns(%d) should not exceed mysql.max_open_conns(%d)",
+				c.MySQLConfig.MaxIdleConns, c.MySQLConfig.MaxOpenConns)
+			for i, r := range c.MySQLConfig.Replicas {
+				check(len(r.Host) > 0, "mysql.replicas[%d].host is required", i)
+			}
+			check(c.MySQLConfig.MaxReplicaLag >= 0, "invalid mysql.max_replica_lag: %v", c.MySQLConfig.MaxReplicaLag)
+			check(c.MySQLConfig.ReplicaCheckInterval >= 0, "invalid mysql.replica_check_interval: %v", c.MySQLConfig.ReplicaCheckInterval)
+		}
+	}
+
+	if c.ShardingConfig != nil {
+		t := c.ShardingConfig.Tables
+		check(t >= 0 && t&(t-1) == 0, "sharding.tables must be a power of 2: %d", t)
+		check(c.ShardingConfig.GeneBits() <= _maxGeneBits,
+			"too many shards: %d databases x %d tables, at most %d", len(c.ShardingConfig.Shards), t, 1<<_maxGeneBits)
+	}
+	if c.ShardingConfig != nil && len(c.ShardingConfig.Shards) > 0 {
+		n := len(c.ShardingConfig.Shards)
+		check(n&(n-1) == 0 && n <= _maxShards, "len(sharding.shards) must be a power of 2 and at most %d: %d", _maxShards, n)
+		dbs := make(map[string]bool, n)
+		for i, s := range c.ShardingConfig.Shards {
+			check(len(s.DB) > 0, "sharding.shards[%d].dbname is required", i)
+			for j, r := range s.Replicas {
+				check(len(r.Host) > 0, "sharding.shards[%d].replicas[%d].host is required", i, j)
+			}
+			key := fmt.Sprintf("%s:%d/%s", s.Host, s.Port, s.DB)
+			check(!dbs[key], "duplicate sharding.shards[%d]: %s", i, key)
+			dbs[key] = true
+		}
+	}
+
+	if c.RedisConfig == nil {
+		check(false, "redis is required")
+	} else {
+		check(len(c.RedisConfig.Host) > 0, "redis.host is required")
+		check(c.RedisConfig.PoolSize >= 0, "invalid redis.pool_size: %d", c.RedisConfig.PoolSize)
+		check(c.RedisConfig.MinIdleConns >= 0, "invalid redis.min_idle_conns: %d", c.RedisConfig.MinIdleConns)
+	}
+
+	if c.ConsulConfig == nil {
+		check(false, "consul is required")
+	} else {
+		check(len(c.ConsulConfig.Addr) > 0, "consul.addr is required")
+	}
+	check(c.GoodsService != nil && len(c.GoodsService.Name) > 0, "goods_service.name is required")
+	check(c.StockService != nil && len(c.StockService.Name) > 0, "stock_service.name is required")
+
+	if c.RocketMqConfig == nil {
+		check(false, "rocketmq is required")
+	} else {
+		mq := c.RocketMqConfig
+		check(len(mq.NameServers) > 0, "rocketmq.name_servers is required")
+		for _, addr := range mq.NameServers {
+			_, _, err := net.SplitHostPort(addr)
+			check(err == nil, "invalid rocketmq.name_servers: %q", addr)
+		}
+		check(len(mq.GroupId) > 0, "rocketmq.group_id is required")
+		check(len(mq.TxGroupId) > 0, "rocketmq.tx_group_id is required")
+		check(len(mq.ConsumerGroupId) > 0, "rocketmq.consumer_group_id is required")
+		check(mq.ProducerRetry >= 0, "invalid rocketmq.producer_retry: %d", mq.ProducerRetry)
+		check(mq.MaxReconsumeTimes > 0, "invalid rocketmq.max_reconsume_times: %d", mq.MaxReconsumeTimes)
+		check(mq.PayTimeoutDelayLevel >= 1 && mq.PayTimeoutDelayLevel <= 18,
+			"invalid rocketmq.pay_timeout_delay_level: %d", mq.PayTimeoutDelayLevel)
+
+		topics := map[string]string{
+			"create_order":         mq.Topic.CreateOrder,
+			"create_order_success": mq.Topic.CreateOrderSuccessfully,
+			"pay_timeout":          mq.Topic.PayTimeOut,
+			"stock_rollback":       mq.Topic.StockRollback,
+			"dead_letter":          mq.Topic.DeadLetter,
+		}
+		seen := make(map[string]string, len(topics))
+		for _, key := range []string{"create_order", "create_order_success", "pay_timeout", "stock_rollback", "dead_letter"} {
+			topic := topics[key]
+			if len(topic) == 0 {
+				check(false, "rocketmq.topic.%s is required", key)
+				continue
+			}
+			// 订单服务按 topic 区分消息，不同用途的 topic 不能相同
+			if other, ok := seen[topic]; ok {
+				check(false, "rocketmq.topic.%s and rocketmq.topic.%s are both %q", other, key, topic)
+			}
+			seen[topic] = key
+		}
+	}
+
+	if c.CacheConfig != nil && c.CacheConfig.Enable {
+		check(c.CacheConfig.OrderTTL > 0, "invalid cache.order_ttl: %s", c.CacheConfig.OrderTTL)
+		check(c.CacheConfig.ListTTL > 0, "invalid cache.list_ttl: %s", c.CacheConfig.ListTTL)
+		check(c.CacheConfig.NotFoundTTL >= 0, "invalid cache.not_found_ttl: %s", c.CacheConfig.NotFoundTTL)
+	}
+
+	if c.LockConfig == nil {
+		check(false, "lock is required")
+	} else {
+		check(c.LockConfig.Expiry >= time.Second, "lock.expiry(%s) should be at least 1s", c.LockConfig.Expiry)
+		check(c.LockConfig.Tries > 0, "invalid lock.tries: %d", c.LockConfig.Tries)
+		check(c.LockConfig.RetryDelay > 0, "invalid lock.retry_delay: %s", c.LockConfig.RetryDelay)
+	}
+
+	if c.LedgerConfig == nil {
+		check(false, "ledger is required")
+	} else {
+		check(c.LedgerConfig.ReconcileInterval >= 0, "invalid ledger.reconcile_interval: %s", c.LedgerConfig.ReconcileInterval)
+		check(c.LedgerConfig.Grace >= 0, "invalid ledger.grace: %s", c.LedgerConfig.Grace)
+		check(c.LedgerConfig.Window > c.LedgerConfig.Grace,
+			"ledger.window(%s) should exceed ledger.grace(%s)", c.LedgerConfig.Window, c.LedgerConfig.Grace)
+	}
+
+	if c.StockReconcileConfig == nil {
+		check(false, "stock_reconcile is required")
+	} else {
+		sr := c.StockReconcileConfig
+		check(sr.Interval >= 0, "invalid stock_reconcile.interval: %s", sr.Interval)
+		check(sr.Window > 0, "invalid stock_reconcile.window: %s", sr.Window)
+		// 事务消息回查等流程可能让订单晚于扣减写入，孤儿判定至少要等一分钟
+		check(sr.OrphanAge >= time.Minute, "stock_reconcile.orphan_age(%s) should be at least 1m", sr.OrphanAge)
+		check(sr.OrphanAge < sr.Window,
+			"stock_reconcile.orphan_age(%s) should be less than stock_reconcile.window(%s)", sr.OrphanAge, sr.Window)
+		check(sr.PageSize > 0 && sr.PageSize <= 1000, "invalid stock_reconcile.page_size: %d", sr.PageSize)
+	}
+
+	if c.ArchiveConfig != nil {
+		ac := c.ArchiveConfig
+		check(ac.Interval >= 0, "invalid archive.interval: %s", ac.Interval)
+		check(ac.BatchInterval >= 0, "invalid archive.batch_interval: %s", ac.BatchInterval)
+		if ac.Interval > 0 {
+			check(ac.BatchSize > 0 && ac.BatchSize <= 10000, "invalid archive.batch_size: %d", ac.BatchSize)
+			// 对账只查询原表，归档的订单必须已经不在对账范围内
+			if c.LedgerConfig != nil {
+				check(ac.MinAge > c.LedgerConfig.Window,
+					"archive.min_age(%s) should exceed ledger.window(%s)", ac.MinAge, c.LedgerConfig.Window)
+			}
+			if c.StockReconcileConfig != nil {
+				check(ac.MinAge > c.StockReconcileConfig.Window,
+					"archive.min_age(%s) should exceed stock_reconcile.window(%s)", ac.MinAge, c.StockReconcileConfig.Window)
+			}
+		}
+	}
+
+	if c.AuthConfig != nil && c.AuthConfig.Enable {
+		ac := c.AuthConfig
+		check(len(ac.HMACSecret) > 0 || len(ac.RSAPublicKey) > 0, "auth.hmac_secret or auth.rsa_public_key is required")
+		check(len(ac.HMACSecret) == 0 || len(ac.HMACSecret) >= _minHMACSecretLen,
+			"auth.hmac_secret should be at least %d bytes", _minHMACSecretLen)
+		check(ac.Leeway >= 0, "invalid auth.leeway: %s", ac.Leeway)
+	}
+
+	if c.TLSConfig != nil {
+		tc := c.TLSConfig
+		check((len(tc.CertFile) > 0) == (len(tc.KeyFile) > 0), "tls.cert_file and tls.key_file should be set together")
+		check(!tc.Enable || len(tc.CertFile) > 0, "tls.cert_file is required when tls.enable is true")
+		check(!tc.ClientAuth || (tc.Enable && len(tc.CAFile) > 0), "tls.client_auth requires tls.enable and tls.ca_file")
+		check(tc.ReloadInterval >= 0, "invalid tls.reload_interval: %s", tc.ReloadInterval)
+	}
+	check(c.TLSConfig != nil || c.GoodsService == nil || !c.GoodsService.TLS, "goods_service.tls requires tls")
+	check(c.TLSConfig != nil || c.StockService == nil || !c.StockService.TLS, "stock_service.tls requires tls")
+
+	if c.RateLimitConfig != nil {
+		methods := make(map[string]bool)
+		for i, r := range c.RateLimitConfig.Rules {
+			check(len(r.Method) > 0, "rate_limit.rules[%d].method is required", i)
+			check(!methods[r.MethodName()], "duplicate rate_limit.rules[%d].method: %s", i, r.Method)
+			methods[r.MethodName()] = true
+			check(r.Rate >= 0 && r.Burst >= 0, "invalid rate_limit.rules[%d]: rate=%v burst=%d", i, r.Rate, r.Burst)
+			check(r.UserLimit >= 0 && (r.UserLimit == 0 || r.UserWindow > 0),
+				"invalid rate_limit.rules[%d]: user_limit=%d user_window=%s", i, r.UserLimit, r.UserWindow)
+			check(r.GoodsLimit >= 0 && (r.GoodsLimit == 0 || r.GoodsWindow > 0),
+				"invalid rate_limit.rules[%d]: goods_limit=%d goods_window=%s", i, r.GoodsLimit, r.GoodsWindow)
+		}
+	}
+
+	if c.PricingConfig != nil {
+		pc := c.PricingConfig
+		check(pc.ShippingFee >= 0, "invalid pricing.shipping_fee: %d", pc.ShippingFee)
+		check(pc.FreeShippingThreshold >= 0, "invalid pricing.free_shipping_threshold: %d", pc.FreeShippingThreshold)
+		for i, p := range pc.Promotions {
+			check(p.Threshold > 0 && p.Discount > 0 && p.Discount <= p.Threshold,
+				"invalid pricing.promotions[%d]: threshold=%d discount=%d", i, p.Threshold, p.Discount)
+		}
+		for i, cp := range pc.Coupons {
+			check(len(cp.Code) > 0, "pricing.coupons[%d].code is required", i)
+			check(cp.MinAmount >= 0 && cp.MaxDiscount >= 0, "invalid pricing.coupons[%d] amount", i)
+			switch cp.Type {
+			case "amount":
+				check(cp.Value > 0, "invalid pricing.coupons[%d].value: %d", i, cp.Value)
+			case "percent":
+				check(cp.Value > 0 && cp.Value < 100, "invalid pricing.coupons[%d].value: %d", i, cp.Value)
+			default:
+				check(false, "invalid pricing.coupons[%d].type: %q", i, cp.Type)
+			}
+		}
+	}
+
+	if c.HealthConfig != nil {
+		check(c.HealthConfig.Interval >= 0, "invalid health.interval: %s", c.HealthConfig.Interval)
+		check(c.HealthConfig.Timeout >= 0, "invalid health.timeout: %s", c.HealthConfig.Timeout)
+		check(c.HealthConfig.Interval == 0 || c.HealthConfig.Timeout <= c.HealthConfig.Interval,
+			"health.timeout(%s) should not exceed health.interval(%s)", c.HealthConfig.Timeout, c.HealthConfig.Interval)
+	}
+	if c.TraceConfig != nil && c.TraceConfig.Enable {
+		check(c.TraceConfig.SampleRatio >= 0 && c.TraceConfig.SampleRatio <= 1,
+			"invalid trace.sample_ratio: %v", c.TraceConfig.SampleRatio)
+		check(c.TraceConfig.Exporter != "otlp" || len(c.TraceConfig.Endpoint) > 0,
+			"trace.endpoint is required when exporter is otlp")
+	}
+
+	return errors.Join(errs...)
+}
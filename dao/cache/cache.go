@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"time"
+
+	"order_service/config"
+	"order_service/dao/redis"
+	"order_service/logger"
+	"order_service/metrics"
+
+	redisv8 "github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+// redis 读穿透缓存
+// 先查 redis，未命中时回源 MySQL 并写回缓存；同一个 key 的并发回源通过 singleflight 合并。
+// redis 出错时直接回源 MySQL，缓存不可用不影响查询。
+// 不存在的记录也会缓存一段时间（not_found_ttl），防止缓存穿透。
+
+const (
+	notFound = "-" // 不存在的记录在缓存中的占位值
+
+	// 数据更新后延迟再删一次缓存，
+	// 避免更新期间并发的查询把旧数据写回缓存；使用从库时至少等待 mysql.max_replica_lag，
+	// 避免回源时从库还没有同步到更新后的数据
+	delayDeleteAfter = time.Second
+)
+
+var group singleflight.Group
+
+// enabled 是否启用缓存，热加载后立即生效
+func enabled() bool {
+	cfg := config.Get().CacheConfig
+	return cfg != nil && cfg.Enable && redis.Client() != nil
+}
+
+// jitter TTL 增加 ±10% 的随机值，避免大量 key 同时过期
+func jitter(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return ttl
+	}
+	delta := int64(ttl) / 10
+	if delta == 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Int63n(2*delta+1)-delta)
+}
+
+// fetch 读穿透查询 key，name 为缓存名称（用于监控指标）
+func fetch[T any](ctx context.Context, name, key string, ttl time.Duration, load func(context.Context) (T, error)) (T, error) {
+	if !enabled() {
+		return load(ctx)
+	}
+
+	var zero T
+	b, err := redis.Client().Get(ctx, key).Bytes()
+	switch {
+	case err == nil:
+		if string(b) == notFound {
+			metrics.CacheRequests.WithLabelValues(name, "hit").Inc()
+			return zero, gorm.ErrRecordNotFound
+		}
+		var v T
+		if err := json.Unmarshal(b, &v); err == nil {
+			metrics.CacheRequests.WithLabelValues(name, "hit").Inc()
+			return v, nil
+		}
+		// 缓存数据格式不对（例如结构体变更），按未命中处理
+		metrics.CacheRequests.WithLabelValues(name, "miss").Inc()
+	case errors.Is(err, redisv8.Nil):
+		metrics.CacheRequests.WithLabelValues(name, "miss").Inc()
+	default:
+		metrics.CacheRequests.WithLabelValues(name, "error").Inc()
+		logger.Ctx(ctx).Warn("get cache failed", zap.String("key", key), zap.Error(err))
+		return load(ctx)
+	}
+
+	// 合并同一个 key 的并发回源，回源不受单个请求取消的影响
+	v, err, _ := group.Do(key, func() (interface{}, error) {
+		loadCtx := context.WithoutCancel(ctx)
+		v, err := load(loadCtx)
+		switch {
+		case err == nil:
+			set(loadCtx, key, v, ttl)
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			setNotFound(loadCtx, key)
+		}
+		return v, err
+	})
+	if err != nil {
+		return zero, err
+	}
+	return v.(T), nil
+}
+
+// set 写入缓存，失败只记录日志
+func set(ctx context.Context, key string, v interface{}, ttl time.Duration) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		logger.Ctx(ctx).Warn("marshal cache value failed", zap.String("key", key), zap.Error(err))
+		return
+	}
+	if err := redis.Client().Set(ctx, key, b, jitter(ttl)).Err(); err != nil {
+		logger.Ctx(ctx).Warn("set cache failed", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// setNotFound 缓存不存在的记录
+func setNotFound(ctx context.Context, key string) {
+	ttl := config.Get().CacheConfig.NotFoundTTL
+	if ttl <= 0 {
+		return
+	}
+	if err := redis.Client().Set(ctx, key, notFound, jitter(ttl)).Err(); err != nil {
+		logger.Ctx(ctx).Warn("set cache failed", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// del 删除缓存，并在 delayDeleteAfter（或者 mysql.max_replica_lag）后再删除一次
+func del(ctx context.Context, keys ...string) {
+	if !enabled() || len(keys) == 0 {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	if err := redis.Client().Del(ctx, keys...).Err(); err != nil {
+		logger.Ctx(ctx).Warn("delete cache failed", zap.Strings("keys", keys), zap.Error(err))
+	}
+	delay := delayDeleteAfter
+	if cfg := config.Get().MySQLConfig; cfg != nil && cfg.MaxReplicaLag > delay {
+		delay = cfg.MaxReplicaLag
+	}
+	time.AfterFunc(delay, func() {
+		if err := redis.Client().Del(ctx, keys...).Err(); err != nil {
+			logger.Ctx(ctx).Warn("delay delete cache failed", zap.Strings("keys", keys), zap.Error(err))
+		}
+	})
+}
@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"order_service/config"
+	"order_service/dao/redis"
+	"order_service/dao/store"
+	"order_service/logger"
+	"order_service/metrics"
+	"order_service/model"
+
+	redisv8 "github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// 订单相关的缓存 key
+// order:info:{order_id}       订单
+// order:detail:{order_id}     订单明细（包含订单状态）
+// order:list:{user_id}        用户订单列表，hash 结构，field 为 {offset}:{limit}
+//
+// 订单状态保存在订单明细中，状态变化只需要删除订单明细的缓存；
+// 订单列表只缓存订单数据，创建、删除、恢复订单时删除该用户的整个列表缓存。
+
+func orderKey(orderId int64) string {
+	return fmt.Sprintf("order:info:%d", orderId)
+}
+
+func detailKey(orderId int64) string {
+	return fmt.Sprintf("order:detail:%d", orderId)
+}
+
+func listKey(userId int64) string {
+	return fmt.Sprintf("order:list:%d", userId)
+}
+
+// orderPage 订单列表的一页数据
+type orderPage struct {
+	Orders []model.Order `json:"orders"`
+	Total  int64         `json:"total"`
+}
+
+// QueryOrder 查询订单，优先读缓存
+func QueryOrder(ctx context.Context, orderId int64) (model.Order, error) {
+	return fetch(ctx, "order", orderKey(orderId), config.Get().CacheConfig.OrderTTL,
+		func(ctx context.Context) (model.Order, error) {
+			return store.Repo().QueryOrder(ctx, orderId)
+		})
+}
+
+// QueryOrderDetail 查询订单明细，优先读缓存
+func QueryOrderDetail(ctx context.Context, orderId int64) (model.OrderDetail, error) {
+	return fetch(ctx, "order_detail", detailKey(orderId), config.Get().CacheConfig.OrderTTL,
+		func(ctx context.Context) (model.OrderDetail, error) {
+			return store.Repo().QueryOrderDetail(ctx, orderId)
+		})
+}
+
+// QueryOrderDetails 批量查询订单明细，命中缓存的直接返回，未命中的一次性回源 MySQL
+func QueryOrderDetails(ctx context.Context, orderIds []int64) ([]model.OrderDetail, error) {
+	if !enabled() || len(orderIds) == 0 {
+		return store.Repo().QueryOrderDetails(ctx, orderIds)
+	}
+
+	keys := make([]string, 0, len(orderIds))
+	for _, id := range orderIds {
+		keys = append(keys, detailKey(id))
+	}
+	vals, err := redis.Client().MGet(ctx, keys...).Result()
+	if err != nil {
+		metrics.CacheRequests.WithLabelValues("order_detail", "error").Add(float64(len(keys)))
+		logger.Ctx(ctx).Warn("mget cache failed", zap.Error(err))
+		return store.Repo().QueryOrderDetails(ctx, orderIds)
+	}
+
+	details := make([]model.OrderDetail, 0, len(orderIds))
+	var missed []int64
+	for i, val := range vals {
+		s, ok := val.(string)
+		if !ok {
+			missed = append(missed, orderIds[i])
+			continue
+		}
+		if s == notFound {
+			continue
+		}
+		var d model.OrderDetail
+		if err := json.Unmarshal([]byte(s), &d); err != nil {
+			missed = append(missed, orderIds[i])
+			continue
+		}
+		details = append(details, d)
+	}
+	metrics.CacheRequests.WithLabelValues("order_detail", "hit").Add(float64(len(orderIds) - len(missed)))
+	if len(missed) == 0 {
+		return details, nil
+	}
+	metrics.CacheRequests.WithLabelValues("order_detail", "miss").Add(float64(len(missed)))
+
+	loaded, err := store.Repo().QueryOrderDetails(ctx, missed)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[int64]bool, len(loaded))
+	ttl := config.Get().CacheConfig.OrderTTL
+	for _, d := range loaded {
+		found[d.OrderId] = true
+		set(ctx, detailKey(d.OrderId), d, ttl)
+	}
+	for _, id := range missed {
+		if !found[id] {
+			setNotFound(ctx, detailKey(id))
+		}
+	}
+	return append(details, loaded...), nil
+}
+
+// QueryOrderList 分页查询用户的订单列表，优先读缓存
+func QueryOrderList(ctx context.Context, userId int64, offset, limit int) ([]model.Order, int64, error) {
+	if !enabled() {
+		return store.Repo().QueryOrderList(ctx, userId, offset, limit)
+	}
+
+	key, field := listKey(userId), fmt.Sprintf("%d:%d", offset, limit)
+	b, err := redis.Client().HGet(ctx, key, field).Bytes()
+	switch {
+	case err == nil:
+		var page orderPage
+		if err := json.Unmarshal(b, &page); err == nil {
+			metrics.CacheRequests.WithLabelValues("order_list", "hit").Inc()
+			return page.Orders, page.Total, nil
+		}
+		metrics.CacheRequests.WithLabelValues("order_list", "miss").Inc()
+	case errors.Is(err, redisv8.Nil):
+		metrics.CacheRequests.WithLabelValues("order_list", "miss").Inc()
+	default:
+		metrics.CacheRequests.WithLabelValues("order_list", "error").Inc()
+		logger.Ctx(ctx).Warn("get cache failed", zap.String("key", key), zap.Error(err))
+		return store.Repo().QueryOrderList(ctx, userId, offset, limit)
+	}
+
+	v, err, _ := group.Do(key+":"+field, func() (interface{}, error) {
+		loadCtx := context.WithoutCancel(ctx)
+		orders, total, err := store.Repo().QueryOrderList(loadCtx, userId, offset, limit)
+		if err != nil {
+			return nil, err
+		}
+		page := orderPage{Orders: orders, Total: total}
+		setListPage(loadCtx, key, field, page)
+		return page, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	page := v.(orderPage)
+	return page.Orders, page.Total, nil
+}
+
+// setListPage 写入一页订单列表，整个列表共用一个过期时间
+func setListPage(ctx context.Context, key, field string, page orderPage) {
+	b, err := json.Marshal(page)
+	if err != nil {
+		logger.Ctx(ctx).Warn("marshal cache value failed", zap.String("key", key), zap.Error(err))
+		return
+	}
+	pipe := redis.Client().TxPipeline()
+	pipe.HSet(ctx, key, field, b)
+	pipe.Expire(ctx, key, jitter(config.Get().CacheConfig.ListTTL))
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Ctx(ctx).Warn("set cache failed", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// CreateOrderWithTransation 创建订单，成功后删除该用户的订单列表缓存和订单的空值缓存
+func CreateOrderWithTransation(ctx context.Context, order *model.Order, orderDetail *model.OrderDetail) error {
+	if err := store.Repo().CreateOrderWithTransation(ctx, order, orderDetail); err != nil {
+		return err
+	}
+	del(ctx, listKey(order.UserId), orderKey(order.OrderId), detailKey(order.OrderId))
+	return nil
+}
+
+// UpdateOrderStatus 更新订单状态，成功后删除订单缓存
+// 所有修改订单状态的地方（接口、超时消息消费者、超时扫描任务）都要通过这里更新，change 写入订单事件
+func UpdateOrderStatus(ctx context.Context, order *model.OrderDetail, change model.StatusChange) error {
+	if err := store.Repo().UpdateOrderStatus(ctx, order, change); err != nil {
+		return err
+	}
+	InvalidateOrder(ctx, order.OrderId)
+	return nil
+}
+
+// DeleteOrder 软删除订单，成功后删除订单缓存和该用户的订单列表缓存
+func DeleteOrder(ctx context.Context, orderId int64) error {
+	order, err := store.Repo().DeleteOrder(ctx, orderId)
+	if err != nil {
+		return err
+	}
+	del(ctx, listKey(order.UserId), orderKey(orderId), detailKey(orderId))
+	return nil
+}
+
+// RestoreOrder 恢复已经软删除的订单，成功后删除订单的空值缓存和该用户的订单列表缓存
+func RestoreOrder(ctx context.Context, orderId int64) error {
+	order, err := store.Repo().RestoreOrder(ctx, orderId)
+	if err != nil {
+		return err
+	}
+	del(ctx, listKey(order.UserId), orderKey(orderId), detailKey(orderId))
+	return nil
+}
+
+// InvalidateOrder 删除订单和订单明细的缓存
+func InvalidateOrder(ctx context.Context, orderId int64) {
+	del(ctx, orderKey(orderId), detailKey(orderId))
+}
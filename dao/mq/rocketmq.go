@@ -1,53 +1,140 @@
 package mq
 
 import (
-	"fmt"                  // 标准库，用于格式化输入输出
+	"context"
+	"errors"
+	"net"
 	"order_service/config" // 自定义配置包，可能包含 RocketMQ 的配置信息
 
 	"github.com/apache/rocketmq-client-go/v2"           // RocketMQ Go 客户端主包
+	"github.com/apache/rocketmq-client-go/v2/consumer"  // 包含消费者相关功能
 	"github.com/apache/rocketmq-client-go/v2/primitive" // 包含 RocketMQ 的基本数据结构，如消息体
 	"github.com/apache/rocketmq-client-go/v2/producer"  // 包含生产者相关功能
+	"go.uber.org/zap"
 )
 
 var (
 	Producer rocketmq.Producer // 全局变量，用于存储 RocketMQ 生产者实例
+	// TxProducer 创建订单的事务消息生产者，所有订单共用，本地事务由 Init 传入的 listener 按消息分发
+	TxProducer rocketmq.TransactionProducer
+
+	pushConsumer rocketmq.PushConsumer // 订单超时消息的消费者
 )
 
-// Init 初始化 RocketMQ 生产者
-func Init() (err error) {
+// ConsumeFunc 消息消费回调
+type ConsumeFunc func(context.Context, ...*primitive.MessageExt) (consumer.ConsumeResult, error)
+
+// Init 初始化 RocketMQ 普通消息生产者和事务消息生产者
+// listener 执行和回查事务消息的本地事务；修改 NameServer、组名等配置后需要重启才能生效
+func Init(listener primitive.TransactionListener) (err error) {
+	cfg := config.Conf.RocketMqConfig
 	// 创建 RocketMQ 生产者实例
 	Producer, err = rocketmq.NewProducer(
 		// 配置名称服务器地址解析器
-		producer.WithNsResolver(primitive.NewPassthroughResolver([]string{config.Conf.RocketMqConfig.Addr})),
-		// 设置消息发送失败时的重试次数为 2 次
-		producer.WithRetry(2),
+		producer.WithNsResolver(primitive.NewPassthroughResolver(cfg.NameServers)),
+		// 设置消息发送失败时的重试次数
+		producer.WithRetry(cfg.ProducerRetry),
 		// 设置生产者所属的组名
-		producer.WithGroupName(config.Conf.RocketMqConfig.GroupId),
+		producer.WithGroupName(cfg.GroupId),
 	)
 	if err != nil {
 		// 如果创建生产者失败，打印错误信息并返回
-		fmt.Println(err)
+		zap.L().Error("rocketmq.NewProducer failed", zap.Error(err))
 		return err
 	}
 	// 启动生产者
 	err = Producer.Start()
 	if err != nil {
 		// 如果启动失败，打印错误信息并返回
-		fmt.Println(err)
+		zap.L().Error("rocketmq producer start failed", zap.Error(err))
 		return
 	}
+
+	// 创建并启动事务消息生产者
+	TxProducer, err = rocketmq.NewTransactionProducer(
+		listener,
+		producer.WithNsResolver(primitive.NewPassthroughResolver(cfg.NameServers)),
+		producer.WithRetry(cfg.ProducerRetry),
+		producer.WithGroupName(cfg.TxGroupId),
+	)
+	if err != nil {
+		zap.L().Error("rocketmq.NewTransactionProducer failed", zap.Error(err))
+		return err
+	}
+	err = TxProducer.Start()
+	if err != nil {
+		zap.L().Error("rocketmq transaction producer start failed", zap.Error(err))
+		return err
+	}
 	// 初始化成功，返回 nil
 	return nil
 }
 
-// Exit 关闭 RocketMQ 生产者
+// StartConsumer 订阅 topic 并启动消费者
+// 消费失败的最大重试次数取 rocketmq.max_reconsume_times
+func StartConsumer(topic string, fn ConsumeFunc) (err error) {
+	cfg := config.Conf.RocketMqConfig
+	pushConsumer, err = rocketmq.NewPushConsumer(
+		consumer.WithGroupName(cfg.ConsumerGroupId),
+		consumer.WithNsResolver(primitive.NewPassthroughResolver(cfg.NameServers)),
+		consumer.WithMaxReconsumeTimes(cfg.MaxReconsumeTimes),
+	)
+	if err != nil {
+		zap.L().Error("rocketmq.NewPushConsumer failed", zap.Error(err))
+		return err
+	}
+	err = pushConsumer.Subscribe(topic, consumer.MessageSelector{}, fn)
+	if err != nil {
+		zap.L().Error("rocketmq consumer subscribe failed", zap.String("topic", topic), zap.Error(err))
+		return err
+	}
+	// Note: start after subscribe
+	err = pushConsumer.Start()
+	if err != nil {
+		zap.L().Error("rocketmq consumer start failed", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Exit 关闭 RocketMQ 消费者和生产者
 func Exit() error {
+	if pushConsumer != nil {
+		if err := pushConsumer.Shutdown(); err != nil {
+			zap.L().Error("shutdown consumer error", zap.Error(err))
+		}
+	}
+	if TxProducer != nil {
+		if err := TxProducer.Shutdown(); err != nil {
+			zap.L().Error("shutdown transaction producer error", zap.Error(err))
+		}
+	}
 	// 调用 Shutdown 方法关闭生产者
 	err := Producer.Shutdown()
 	if err != nil {
 		// 如果关闭失败，打印错误信息
-		fmt.Printf("shutdown producer error: %s", err.Error())
+		zap.L().Error("shutdown producer error", zap.Error(err))
 	}
 	// 返回关闭操作的结果
 	return err
 }
+
+// Ping 检查 RocketMQ 名称服务器是否可达（供健康检查使用）
+// rocketmq-client-go 没有提供探活接口，这里直接对 NameServer 地址建立 TCP 连接，任意一个可达即可
+func Ping(ctx context.Context) error {
+	if Producer == nil {
+		return errors.New("rocketmq producer not initialized")
+	}
+	var (
+		d   net.Dialer
+		err error
+	)
+	for _, addr := range config.Get().RocketMqConfig.NameServers {
+		var conn net.Conn
+		conn, err = d.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			return conn.Close()
+		}
+	}
+	return err
+}
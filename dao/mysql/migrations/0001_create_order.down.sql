@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `xx_order_detail`;
+DROP TABLE IF EXISTS `xx_order`;
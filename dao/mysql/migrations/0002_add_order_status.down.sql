@@ -0,0 +1,5 @@
+ALTER TABLE `xx_order_detail`
+    DROP INDEX `idx_status_create_at`,
+    DROP COLUMN `status`;
+
+ALTER TABLE `xx_order` DROP COLUMN `status`;
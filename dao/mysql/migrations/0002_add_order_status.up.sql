@@ -0,0 +1,7 @@
+-- 订单状态：pending/unpaid 待支付 paid 已支付 shipped 已发货 completed 已完成 cancelled 已取消 timeout 支付超时
+ALTER TABLE `xx_order`
+    ADD COLUMN `status` VARCHAR(16) NOT NULL DEFAULT 'pending' COMMENT '订单状态' AFTER `pay_amount`;
+
+ALTER TABLE `xx_order_detail`
+    ADD COLUMN `status` VARCHAR(16) NOT NULL DEFAULT 'pending' COMMENT '订单状态' AFTER `title`,
+    ADD INDEX `idx_status_create_at` (status, create_at);
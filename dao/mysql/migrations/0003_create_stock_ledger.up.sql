@@ -0,0 +1,13 @@
+-- 库存补偿台账
+CREATE TABLE IF NOT EXISTS `xx_stock_ledger`(
+    `id` BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY COMMENT '主键',
+    `create_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `update_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '更新时间',
+    `order_id` BIGINT(20) UNSIGNED NOT NULL COMMENT '订单id',
+    `goods_id` BIGINT(20) UNSIGNED NOT NULL COMMENT '商品id',
+    `num` BIGINT(20) UNSIGNED NOT NULL COMMENT '扣减数量',
+    `status` VARCHAR(16) NOT NULL COMMENT '状态：deducted已扣减 rolling_back回滚中 rolled_back已回滚',
+    `source` VARCHAR(32) NOT NULL DEFAULT '' COMMENT '回滚来源',
+    UNIQUE KEY `uk_order_goods` (order_id, goods_id),
+    INDEX (status, update_at)
+)ENGINE=INNODB DEFAULT CHARSET=utf8mb4 COMMENT = '库存补偿台账';
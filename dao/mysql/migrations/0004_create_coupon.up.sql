@@ -0,0 +1,23 @@
+-- 发放给用户的优惠券
+CREATE TABLE IF NOT EXISTS `xx_coupon`(
+    `id` BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY COMMENT '主键',
+    `create_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `create_by` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '创建者',
+    `update_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '更新时间',
+    `update_by` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '更新者',
+    `version` SMALLINT(5) UNSIGNED NOT NULL DEFAULT '0' COMMENT '乐观锁版本号',
+    `is_del` TINYINT(4) UNSIGNED NOT NULL DEFAULT '0' COMMENT '是否删除：0正常1删除',
+    `code` VARCHAR(64) NOT NULL COMMENT '券码',
+    `user_id` BIGINT(20) UNSIGNED NOT NULL COMMENT '用户id',
+    `type` VARCHAR(16) NOT NULL COMMENT '类型：amount立减 percent折扣',
+    `value` BIGINT(20) UNSIGNED NOT NULL COMMENT '立减金额（分）或折扣百分比',
+    `min_amount` BIGINT(20) UNSIGNED NOT NULL DEFAULT '0' COMMENT '使用门槛（分）',
+    `max_discount` BIGINT(20) UNSIGNED NOT NULL DEFAULT '0' COMMENT '折扣券最高减免（分）',
+    `status` VARCHAR(16) NOT NULL DEFAULT 'unused' COMMENT '状态：unused未使用 reserved已锁定 used已使用',
+    `order_id` BIGINT(20) UNSIGNED NOT NULL DEFAULT '0' COMMENT '使用该券的订单id',
+    `expire_at` DATETIME NOT NULL COMMENT '过期时间',
+    UNIQUE KEY `uk_code` (code),
+    INDEX (user_id),
+    INDEX (order_id),
+    INDEX (is_del)
+)ENGINE=INNODB DEFAULT CHARSET=utf8mb4 COMMENT = '优惠券表';
@@ -0,0 +1,49 @@
+-- 订单归档表，保存已完成、已取消、支付超时并且超过 archive.min_age 的订单
+-- 列和订单表、订单商品表相同（id 保留原表的主键），修改订单表结构时要同步修改归档表
+CREATE TABLE IF NOT EXISTS `xx_order_archive`(
+    `id` BIGINT(20) UNSIGNED NOT NULL PRIMARY KEY COMMENT '订单表中的主键',
+    `create_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `create_by` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '创建者',
+    `update_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '更新时间',
+    `update_by` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '更新者',
+    `version` SMALLINT(5) UNSIGNED NOT NULL DEFAULT '0' COMMENT '乐观锁版本号',
+    `is_del` TINYINT(4) UNSIGNED NOT NULL DEFAULT '0' COMMENT '是否删除：0正常1删除',
+    `user_id` BIGINT(20) UNSIGNED NOT NULL COMMENT '用户id',
+    `order_id` BIGINT(20) UNSIGNED NOT NULL COMMENT '订单id',
+    `pay_amount` BIGINT(20) UNSIGNED NOT NULL DEFAULT '0' COMMENT '支付金额（分）',
+    `status` VARCHAR(16) NOT NULL DEFAULT 'pending' COMMENT '订单状态',
+    `receive_address` VARCHAR(128) NOT NULL DEFAULT '' COMMENT '收货地址',
+    `receive_name` VARCHAR(128) NOT NULL DEFAULT '' COMMENT '收货人',
+    `receive_phone` VARCHAR(11) NOT NULL DEFAULT '' COMMENT '收货人电话',
+    `archived_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '归档时间',
+    UNIQUE KEY `uk_order_id` (order_id),
+    INDEX (user_id)
+)ENGINE=INNODB DEFAULT CHARSET=utf8mb4 COMMENT = '订单归档表';
+
+CREATE TABLE IF NOT EXISTS `xx_order_detail_archive`(
+    `id` BIGINT(20) UNSIGNED NOT NULL PRIMARY KEY COMMENT '订单商品表中的主键',
+    `create_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `create_by` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '创建者',
+    `update_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '更新时间',
+    `update_by` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '更新者',
+    `version` SMALLINT(5) UNSIGNED NOT NULL DEFAULT '0' COMMENT '乐观锁版本号',
+    `is_del` TINYINT(4) UNSIGNED NOT NULL DEFAULT '0' COMMENT '是否删除：0正常1删除',
+    `user_id` BIGINT(20) UNSIGNED NOT NULL COMMENT '用户id',
+    `order_id` BIGINT(20) UNSIGNED NOT NULL COMMENT '订单id',
+    `goods_id` BIGINT(20) UNSIGNED NOT NULL COMMENT '商品id',
+    `title` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '名称',
+    `status` VARCHAR(16) NOT NULL DEFAULT 'pending' COMMENT '订单状态',
+    `price` BIGINT(20) UNSIGNED NOT NULL DEFAULT '0' COMMENT '售价（分）',
+    `brief` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '简介',
+    `num` BIGINT(20) UNSIGNED NOT NULL COMMENT '商品数量',
+    `pay_amount` BIGINT(20) UNSIGNED NOT NULL DEFAULT '0' COMMENT '支付金额（分）',
+    `subtotal` BIGINT(20) UNSIGNED NOT NULL DEFAULT '0' COMMENT '小计（分）',
+    `promotion_discount` BIGINT(20) UNSIGNED NOT NULL DEFAULT '0' COMMENT '满减金额（分）',
+    `coupon_code` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '优惠券码',
+    `coupon_discount` BIGINT(20) UNSIGNED NOT NULL DEFAULT '0' COMMENT '优惠券减免金额（分）',
+    `shipping_fee` BIGINT(20) UNSIGNED NOT NULL DEFAULT '0' COMMENT '运费（分）',
+    `fence_token` BIGINT(20) UNSIGNED NOT NULL DEFAULT '0' COMMENT '最近一次修改状态时的订单锁 fencing token',
+    `archived_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '归档时间',
+    INDEX (order_id),
+    INDEX (user_id)
+)ENGINE=INNODB DEFAULT CHARSET=utf8mb4 COMMENT = '订单商品归档表';
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `xx_order_event`;
@@ -0,0 +1,13 @@
+-- 订单事件表，记录订单的每一次状态变化，只追加不修改
+CREATE TABLE IF NOT EXISTS `xx_order_event`(
+    `id` BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY COMMENT '主键',
+    `create_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '状态变化时间',
+    `order_id` BIGINT(20) UNSIGNED NOT NULL COMMENT '订单id',
+    `user_id` BIGINT(20) UNSIGNED NOT NULL COMMENT '用户id',
+    `from_status` VARCHAR(16) NOT NULL COMMENT '变化前的状态，创建订单时为空',
+    `to_status` VARCHAR(16) NOT NULL COMMENT '变化后的状态',
+    `actor` VARCHAR(64) NOT NULL COMMENT '操作者',
+    `source` VARCHAR(32) NOT NULL COMMENT '来源：create_order rpc timeout_consumer timeout_scanner',
+    `reason` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '原因',
+    INDEX (order_id)
+)ENGINE=INNODB DEFAULT CHARSET=utf8mb4 COMMENT = '订单事件表';
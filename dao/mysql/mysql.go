@@ -1,31 +1,114 @@
 package mysql
 
 import (
+	"database/sql"
+	"embed"
+	"errors"
 	"fmt"
+	"io/fs"
 	"order_service/config"
+	"order_service/dao/orm"
+	"slices"
+	"strconv"
 	"time"
 
+	"go.uber.org/zap"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	gormtracing "gorm.io/plugin/opentelemetry/tracing"
 )
 
 // https://gorm.io/zh_CN/docs/connecting_to_the_database.html
 
-var db *gorm.DB
+// MySQL 存储后端，线上使用
 
-// 初始化MySQL连接
-func Init(cfg *config.MySQLConfig) (err error) {
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// _migrateLockName 迁移期间持有的 MySQL 命名锁，避免多个实例同时执行迁移
+const _migrateLockName = "order_service:migrate"
+
+const _migrationTableDDL = "CREATE TABLE IF NOT EXISTS `schema_migrations`(" +
+	"`version` BIGINT(20) NOT NULL PRIMARY KEY COMMENT '迁移版本号'," +
+	"`name` VARCHAR(128) NOT NULL DEFAULT '' COMMENT '迁移名称'," +
+	"`dirty` TINYINT(1) NOT NULL DEFAULT '0' COMMENT '是否执行中或执行失败'," +
+	"`applied_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '执行时间'" +
+	")ENGINE=INNODB DEFAULT CHARSET=utf8mb4 COMMENT = '数据库迁移记录'"
+
+// 从库配置的默认值
+const (
+	_defaultMaxReplicaLag        = time.Second
+	_defaultReplicaCheckInterval = 5 * time.Second
+)
+
+// Open 连接 MySQL 主库和所有从库，返回订单存储
+func Open(cfg *config.MySQLConfig) (*orm.Repository, error) {
+	db, err := connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := fs.Sub(migrationFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	r, err := orm.New(db, &orm.Dialect{
+		Name:              cfg.DB,
+		Migrations:        migrations,
+		MigrationTableDDL: _migrationTableDDL,
+		Lock:              lock,
+		ReplicaLag:        replicaLag,
+		CopyTable:         copyTable,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 从库使用和主库相同的账号和库名
+	for _, rc := range cfg.Replicas {
+		replicaCfg := *cfg
+		replicaCfg.Host = rc.Host
+		if rc.Port > 0 {
+			replicaCfg.Port = rc.Port
+		}
+		replica, err := connect(&replicaCfg)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("connect replica %s:%d failed: %w", replicaCfg.Host, replicaCfg.Port, err)
+		}
+		r.AddReplica(fmt.Sprintf("%s:%d", replicaCfg.Host, replicaCfg.Port), replica)
+	}
+	r.SetMaxReplicaLag(maxReplicaLag(cfg))
+	interval := cfg.ReplicaCheckInterval
+	if interval <= 0 {
+		interval = _defaultReplicaCheckInterval
+	}
+	r.StartReplicaMonitor(interval)
+	return r, nil
+}
+
+// connect 连接 cfg 中 host/port 指定的库
+func connect(cfg *config.MySQLConfig) (*gorm.DB, error) {
 	// 参考 https://github.com/go-sql-driver/mysql#dsn-data-source-name 获取详情
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DB)
-	db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// 链路追踪：每条 SQL 作为一个 span 记录到调用方的 trace 中
+	err = db.Use(gormtracing.NewPlugin(
+		gormtracing.WithoutMetrics(),
+		gormtracing.WithDBName(cfg.DB),
+	))
+	if err != nil {
+		return nil, err
 	}
 
 	// 额外的连接配置
 	sqlDB, err := db.DB() // database/sql.DB
 	if err != nil {
-		return
+		return nil, err
 	}
 
 	// 以下配置要配合 my.conf 进行配置
@@ -37,5 +120,99 @@ func Init(cfg *config.MySQLConfig) (err error) {
 
 	// SetConnMaxLifetime 设置了连接可复用的最大时间。
 	sqlDB.SetConnMaxLifetime(time.Hour)
-	return
+	return db, nil
+}
+
+func maxReplicaLag(cfg *config.MySQLConfig) time.Duration {
+	if cfg.MaxReplicaLag <= 0 {
+		return _defaultMaxReplicaLag
+	}
+	return cfg.MaxReplicaLag
+}
+
+// replicaLag 查询从库的复制延迟（Seconds_Behind_Source）
+// MySQL 8.0.22 之前没有 SHOW REPLICA STATUS，使用 SHOW SLAVE STATUS；
+// 不是从库或者复制线程没有运行时返回错误
+func replicaLag(db *gorm.DB) (time.Duration, error) {
+	status, err := showStatus(db, "SHOW REPLICA STATUS")
+	if err != nil {
+		status, err = showStatus(db, "SHOW SLAVE STATUS")
+	}
+	if err != nil {
+		return 0, err
+	}
+	if status == nil {
+		return 0, errors.New("not a replica")
+	}
+	seconds, ok := status["Seconds_Behind_Source"]
+	if !ok {
+		seconds = status["Seconds_Behind_Master"]
+	}
+	if !seconds.Valid {
+		return 0, errors.New("replication is not running")
+	}
+	n, err := strconv.ParseInt(seconds.String, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(n) * time.Second, nil
+}
+
+// showStatus 执行 SHOW ... STATUS，返回第一行的 列名 -> 值，没有数据时返回 nil
+func showStatus(db *gorm.DB, query string) (map[string]sql.NullString, error) {
+	rows, err := db.Raw(query).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+	status := make(map[string]sql.NullString, len(columns))
+	for i, c := range columns {
+		status[c] = values[i]
+	}
+	return status, nil
+}
+
+// lock 使用 MySQL 命名锁，锁和连接绑定，必须在同一个连接上释放
+func lock(tx *gorm.DB) (func(), error) {
+	var got int
+	if err := tx.Raw("SELECT GET_LOCK(?, 10)", _migrateLockName).Scan(&got).Error; err != nil {
+		return nil, err
+	}
+	if got != 1 {
+		return nil, errors.New("another migration is running")
+	}
+	return func() {
+		tx.Exec("SELECT RELEASE_LOCK(?)", _migrateLockName)
+	}, nil
+}
+
+// copyTable 按模板表创建第 n 张分表，CREATE TABLE ... LIKE 同时复制列和索引
+func copyTable(tx *gorm.DB, table string, n int) error {
+	return tx.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` LIKE `%s`", orm.SplitTableName(table, n), table)).Error
+}
+
+// Reload 配置热加载时调整连接池大小和允许的最大复制延迟
+// 连接地址、账号、从库列表等信息变化需要重启服务才能生效
+func Reload(r *orm.Repository, old, cur *config.MySQLConfig) error {
+	if old.Host != cur.Host || old.Port != cur.Port || old.User != cur.User ||
+		old.Password != cur.Password || old.DB != cur.DB || !slices.Equal(old.Replicas, cur.Replicas) {
+		zap.L().Warn("mysql connection config changed, restart required to take effect")
+	}
+	r.SetMaxReplicaLag(maxReplicaLag(cur))
+	return r.SetConnPool(cur.MaxIdleConns, cur.MaxOpenConns)
 }
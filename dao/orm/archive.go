@@ -0,0 +1,152 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"order_service/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 冷订单归档
+// 已完成、已取消、支付超时并且创建时间早于 before 的订单，分批从订单表、订单商品表移动到归档表，
+// 选出的订单明细加行锁，插入归档表和从原表删除在同一个事务中完成。
+// 已取消、支付超时的订单库存台账还没有回滚完成时不归档，留给台账对账处理。
+// 已经软删除的订单同样归档，is_del 随其他列一起复制，归档后仍然查不到，也可以恢复。
+// 按订单号查询订单、订单明细时，原表中找不到会再查一次归档表，调用方不需要区分订单是否已经归档。
+
+const (
+	_orderArchiveTable  = "xx_order_archive"
+	_detailArchiveTable = "xx_order_detail_archive"
+)
+
+// ArchiveOrders 归档一批订单，返回归档的订单数，返回值小于 limit 说明已经没有可以归档的订单
+func (r *Repository) ArchiveOrders(ctx context.Context, before time.Time, limit int) (int, error) {
+	orderCols, err := r.columns(&model.Order{})
+	if err != nil {
+		return 0, err
+	}
+	detailCols, err := r.columns(&model.OrderDetail{})
+	if err != nil {
+		return 0, err
+	}
+
+	var orderIds []int64
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 锁住选中的订单明细，复制和删除完成前状态更新（UpdateOrderStatus 同样先锁订单明细）需要等待，归档的不会是过期的状态
+		err := r.details(tx).
+			Unscoped().
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("create_at < ?", before).
+			Where("status = ? OR (status IN ? AND NOT EXISTS (?))",
+				model.OrderStatusCompleted,
+				[]string{model.OrderStatusCancelled, model.OrderStatusTimeout},
+				tx.Session(&gorm.Session{NewDB: true}).
+					Model(&model.StockLedger{}).
+					Select("1").
+					Where(fmt.Sprintf("xx_stock_ledger.order_id = %s.order_id AND xx_stock_ledger.status <> ?", r.detailTable()), model.LedgerStatusRolledBack),
+			).
+			Order("id").
+			Limit(limit).
+			Pluck("order_id", &orderIds).Error
+		if err != nil || len(orderIds) == 0 {
+			return err
+		}
+
+		now := time.Now()
+		tables := []struct {
+			table, archive string
+			cols           string
+		}{
+			{r.orderTable(), _orderArchiveTable, orderCols},
+			{r.detailTable(), _detailArchiveTable, detailCols},
+		}
+		for _, t := range tables {
+			err := tx.Exec(fmt.Sprintf("INSERT INTO `%s` (%s, `archived_at`) SELECT %s, ? FROM `%s` WHERE order_id IN ?",
+				t.archive, t.cols, t.cols, t.table), now, orderIds).Error
+			if err != nil {
+				return fmt.Errorf("archive %s failed: %w", t.table, err)
+			}
+			if err := tx.Exec(fmt.Sprintf("DELETE FROM `%s` WHERE order_id IN ?", t.table), orderIds).Error; err != nil {
+				return fmt.Errorf("delete archived %s failed: %w", t.table, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(orderIds), nil
+}
+
+// columns 模型对应的列名列表，用于在原表和归档表之间复制数据
+func (r *Repository) columns(v interface{}) (string, error) {
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(v); err != nil {
+		return "", err
+	}
+	cols := make([]string, 0, len(stmt.Schema.DBNames))
+	for _, name := range stmt.Schema.DBNames {
+		cols = append(cols, "`"+name+"`")
+	}
+	return strings.Join(cols, ", "), nil
+}
+
+// queryArchivedOrder 在归档表中查询订单
+func (r *Repository) queryArchivedOrder(ctx context.Context, orderId int64) (model.Order, error) {
+	var data model.Order
+	err := r.reader(ctx).
+		Table(_orderArchiveTable).
+		Where("order_id = ?", orderId).
+		First(&data).Error
+	return data, err
+}
+
+// queryArchivedOrderDetail 在归档表中查询订单明细
+func (r *Repository) queryArchivedOrderDetail(ctx context.Context, orderId int64) (model.OrderDetail, error) {
+	var data model.OrderDetail
+	err := r.reader(ctx).
+		Table(_detailArchiveTable).
+		Where("order_id = ?", orderId).
+		First(&data).Error
+	return data, err
+}
+
+// queryArchivedOrderDetails 在归档表中批量查询订单明细
+func (r *Repository) queryArchivedOrderDetails(ctx context.Context, orderIds []int64) ([]model.OrderDetail, error) {
+	var details []model.OrderDetail
+	err := r.reader(ctx).
+		Table(_detailArchiveTable).
+		Where("order_id IN ?", orderIds).
+		Find(&details).Error
+	return details, err
+}
+
+// queryArchivedOrderIds 查询 orderIds 中已经归档的订单ID
+func (r *Repository) queryArchivedOrderIds(ctx context.Context, orderIds []int64) ([]int64, error) {
+	var ids []int64
+	err := r.db.WithContext(ctx).
+		Table(_orderArchiveTable).
+		Where("order_id IN ?", orderIds).
+		Pluck("order_id", &ids).Error
+	return ids, err
+}
+
+// missingOrderIds orderIds 中不在 found 里的订单号
+func missingOrderIds(orderIds []int64, found []int64) []int64 {
+	exists := make(map[int64]bool, len(found))
+	for _, id := range found {
+		exists[id] = true
+	}
+	var missing []int64
+	for _, id := range orderIds {
+		if !exists[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
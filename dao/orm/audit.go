@@ -0,0 +1,44 @@
+package orm
+
+import (
+	"order_service/auth"
+
+	"gorm.io/gorm"
+)
+
+// 审计列
+// 插入时填充 create_by、update_by，更新时填充 update_by，操作者取自 ctx 中的调用方（auth.Actor）。
+// 只对通过模型写入的数据生效，Exec 执行的原生 SQL（例如归档时的 INSERT ... SELECT）不修改审计列。
+
+const (
+	_createByColumn = "create_by"
+	_updateByColumn = "update_by"
+)
+
+// registerAuditCallbacks 在写入前填充审计列
+func registerAuditCallbacks(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("order:audit", fillCreateBy); err != nil {
+		return err
+	}
+	return cb.Update().Before("gorm:update").Register("order:audit", fillUpdateBy)
+}
+
+func fillCreateBy(db *gorm.DB) {
+	if db.Statement.Schema == nil {
+		return
+	}
+	actor := auth.Actor(db.Statement.Context)
+	for _, column := range []string{_createByColumn, _updateByColumn} {
+		if db.Statement.Schema.LookUpField(column) != nil {
+			db.Statement.SetColumn(column, actor, true)
+		}
+	}
+}
+
+func fillUpdateBy(db *gorm.DB) {
+	if db.Statement.Schema == nil || db.Statement.Schema.LookUpField(_updateByColumn) == nil {
+		return
+	}
+	db.Statement.SetColumn(_updateByColumn, auth.Actor(db.Statement.Context), true)
+}
@@ -0,0 +1,63 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"order_service/model"
+
+	"gorm.io/gorm"
+)
+
+// QueryCoupon 根据券码查询优惠券
+func (r *Repository) QueryCoupon(ctx context.Context, code string) (model.Coupon, error) {
+	var data model.Coupon
+	err := r.db.WithContext(ctx).
+		Model(&model.Coupon{}).
+		Where("code = ?", code).
+		First(&data).Error
+	return data, err
+}
+
+// ReserveCoupon 为订单锁定一张未使用的优惠券，返回 false 表示券已经被使用或者不属于该用户
+func (r *Repository) ReserveCoupon(ctx context.Context, code string, userId, orderId int64) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.Coupon{}).
+		Where("code = ? AND user_id = ? AND status = ? AND expire_at > ?", code, userId, model.CouponStatusUnused, time.Now()).
+		Updates(map[string]interface{}{
+			"status":   model.CouponStatusReserved,
+			"order_id": orderId,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ConfirmCoupon 订单支付后核销订单锁定的优惠券
+func (r *Repository) ConfirmCoupon(ctx context.Context, orderId int64) error {
+	return r.db.WithContext(ctx).
+		Model(&model.Coupon{}).
+		Where("order_id = ? AND status = ?", orderId, model.CouponStatusReserved).
+		Update("status", model.CouponStatusUsed).Error
+}
+
+// ReleaseCoupon 释放订单锁定的优惠券，返回释放的券码，订单没有锁定优惠券时返回空字符串
+func (r *Repository) ReleaseCoupon(ctx context.Context, orderId int64) (string, error) {
+	var c model.Coupon
+	err := r.db.WithContext(ctx).
+		Where("order_id = ? AND status = ?", orderId, model.CouponStatusReserved).
+		First(&c).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	err = r.db.WithContext(ctx).
+		Model(&model.Coupon{}).
+		Where("id = ? AND order_id = ? AND status = ?", c.ID, orderId, model.CouponStatusReserved).
+		Updates(map[string]interface{}{
+			"status":   model.CouponStatusUnused,
+			"order_id": 0,
+		}).Error
+	return c.Code, err
+}
@@ -0,0 +1,71 @@
+package orm
+
+import (
+	"context"
+	"errors"
+
+	"order_service/errno"
+	"order_service/model"
+
+	"gorm.io/gorm"
+)
+
+// 订单软删除
+// 只有已经结束的订单可以删除，订单和订单明细在同一个事务中修改 is_del，
+// 删除后查询订单、订单列表、订单明细都查不到（见 model/soft_delete.go），RestoreOrder 可以恢复。
+// 订单已经归档时修改归档表中的数据。
+
+// DeleteOrder 软删除订单，返回删除的订单
+func (r *Repository) DeleteOrder(ctx context.Context, orderId int64) (model.Order, error) {
+	return r.setOrderDeleted(ctx, orderId, model.Deleted)
+}
+
+// RestoreOrder 恢复已经软删除的订单，返回恢复的订单
+func (r *Repository) RestoreOrder(ctx context.Context, orderId int64) (model.Order, error) {
+	return r.setOrderDeleted(ctx, orderId, model.NotDeleted)
+}
+
+// setOrderDeleted 修改订单和订单明细的删除标记，订单不存在或者已经是 flag 时返回 ErrOrderNotFound
+func (r *Repository) setOrderDeleted(ctx context.Context, orderId int64, flag model.DeleteFlag) (model.Order, error) {
+	var order model.Order
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tables := []struct {
+			order, detail string
+		}{
+			{r.orderTable(), r.detailTable()},
+			{_orderArchiveTable, _detailArchiveTable},
+		}
+		for _, t := range tables {
+			err := tx.Unscoped().
+				Table(t.order).
+				Where("order_id = ? AND is_del <> ?", orderId, flag).
+				First(&order).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if flag == model.Deleted && !model.IsFinalStatus(order.Status) {
+				return errno.ErrOrderNotDeletable
+			}
+
+			// 通过 Model 更新，update_by 由审计回调填充
+			err = tx.Unscoped().
+				Model(&model.Order{}).
+				Table(t.order).
+				Where("order_id = ?", orderId).
+				Update("is_del", flag).Error
+			if err != nil {
+				return err
+			}
+			return tx.Unscoped().
+				Model(&model.OrderDetail{}).
+				Table(t.detail).
+				Where("order_id = ?", orderId).
+				Update("is_del", flag).Error
+		}
+		return errno.ErrOrderNotFound
+	})
+	return order, err
+}
@@ -0,0 +1,37 @@
+package orm
+
+import (
+	"context"
+
+	"order_service/auth"
+	"order_service/model"
+
+	"gorm.io/gorm"
+)
+
+// 订单事件
+// 订单的每一次状态变化（包括创建）都在修改状态的事务中追加一条事件，记录变化前后的状态、操作者、来源和原因，
+// 用于处理客诉时查询订单的历史。事件按订单号分片，和订单明细在同一个库中。
+
+// createOrderEvent 在 tx 中写入一条订单事件，操作者取自 ctx 中的调用方
+func createOrderEvent(tx *gorm.DB, orderId, userId int64, from, to string, change model.StatusChange) error {
+	return tx.Create(&model.OrderEvent{
+		OrderId:    orderId,
+		UserId:     userId,
+		FromStatus: from,
+		ToStatus:   to,
+		Actor:      auth.Actor(tx.Statement.Context),
+		Source:     change.Source,
+		Reason:     change.Reason,
+	}).Error
+}
+
+// QueryOrderEvents 按时间顺序查询订单的全部事件（读主库，事件需要和订单状态一致）
+func (r *Repository) QueryOrderEvents(ctx context.Context, orderId int64) ([]model.OrderEvent, error) {
+	var events []model.OrderEvent
+	err := r.db.WithContext(ctx).
+		Where("order_id = ?", orderId).
+		Order("id").
+		Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,447 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"order_service/logger"
+	"order_service/model"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 数据库表结构迁移
+// 迁移脚本由各个后端编译进二进制（Dialect.Migrations），已执行的版本记录在 schema_migrations 表中。
+// MySQL 的 DDL 不能回滚，执行前先把版本标记为 dirty，执行成功后清除，
+// 执行失败时需要人工修复数据库后用 migrate force 清除标记。
+
+// _models 需要和数据库表结构保持一致的模型，启动时逐个检查
+var _models = []interface{}{
+	&model.Order{},
+	&model.OrderDetail{},
+	&model.StockLedger{},
+	&model.Coupon{},
+	&model.OrderEvent{},
+}
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration 一个版本的迁移脚本
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState 迁移版本的执行状态
+type MigrationState struct {
+	Database  string     `json:"database"`
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	Dirty     bool       `json:"dirty"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// schemaMigration schema_migrations 表中的一条记录
+type schemaMigration struct {
+	Version   int64     `gorm:"column:version;primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"column:name"`
+	Dirty     bool      `gorm:"column:dirty"`
+	AppliedAt time.Time `gorm:"column:applied_at"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// loadMigrations 读取编译进二进制的迁移脚本，按版本号升序返回
+func (r *Repository) loadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(r.dialect.Migrations, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		m := migrationFileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", e.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		b, err := fs.ReadFile(r.dialect.Migrations, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mg
+		}
+		if mg.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has different names: %s, %s", version, mg.Name, m[2])
+		}
+		if m[3] == "up" {
+			mg.Up = string(b)
+		} else {
+			mg.Down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if len(mg.Up) == 0 || len(mg.Down) == 0 {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down scripts", mg.Version, mg.Name)
+		}
+		migrations = append(migrations, *mg)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// MigrateUp 按顺序执行未执行的迁移，steps <= 0 表示全部执行，返回本次执行的迁移
+func (r *Repository) MigrateUp(ctx context.Context, steps int) (done []Migration, err error) {
+	migrations, err := r.loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	err = r.withMigrateLock(ctx, func(tx *gorm.DB) error {
+		applied, err := appliedMigrations(tx)
+		if err != nil {
+			return err
+		}
+		if err := r.createSplitTables(tx); err != nil {
+			return err
+		}
+		for _, mg := range migrations {
+			if steps > 0 && len(done) >= steps {
+				break
+			}
+			if _, ok := applied[mg.Version]; ok {
+				continue
+			}
+			if err := r.runMigration(tx, mg, true); err != nil {
+				return err
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// MigrateDown 按版本号倒序回退已执行的迁移，steps <= 0 表示全部回退，返回本次回退的迁移
+func (r *Repository) MigrateDown(ctx context.Context, steps int) (done []Migration, err error) {
+	migrations, err := r.loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	err = r.withMigrateLock(ctx, func(tx *gorm.DB) error {
+		applied, err := appliedMigrations(tx)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0; i-- {
+			if steps > 0 && len(done) >= steps {
+				break
+			}
+			mg := migrations[i]
+			if _, ok := applied[mg.Version]; !ok {
+				continue
+			}
+			if err := r.runMigration(tx, mg, false); err != nil {
+				return err
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// ForceMigration 把数据库标记为已经执行到 version 版本（包括 version），并清除 dirty 标记
+// 用于迁移失败、人工修复数据库之后，version 为 0 表示清空所有记录
+func (r *Repository) ForceMigration(ctx context.Context, version int64) error {
+	migrations, err := r.loadMigrations()
+	if err != nil {
+		return err
+	}
+	if version != 0 && !hasVersion(migrations, version) {
+		return fmt.Errorf("unknown migration version: %d", version)
+	}
+	return r.withMigrateLock(ctx, func(tx *gorm.DB) error {
+		if err := tx.Where("version > ?", version).Delete(&schemaMigration{}).Error; err != nil {
+			return err
+		}
+		for _, mg := range migrations {
+			if mg.Version > version {
+				break
+			}
+			err := tx.Clauses(clause.OnConflict{
+				DoUpdates: clause.AssignmentColumns([]string{"dirty"}),
+			}).Create(&schemaMigration{Version: mg.Version, Name: mg.Name, AppliedAt: time.Now()}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MigrationStatus 返回每个迁移版本的执行状态
+func (r *Repository) MigrationStatus(ctx context.Context) ([]MigrationState, error) {
+	migrations, err := r.loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	tx := r.db.WithContext(ctx)
+	if err := tx.Exec(r.dialect.MigrationTableDDL).Error; err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, mg := range migrations {
+		st := MigrationState{Database: r.dialect.Name, Version: mg.Version, Name: mg.Name}
+		if r, ok := applied[mg.Version]; ok {
+			st.Applied = true
+			st.Dirty = r.Dirty
+			st.AppliedAt = &r.AppliedAt
+		}
+		states = append(states, st)
+	}
+	return states, nil
+}
+
+// CheckSchema 检查数据库表结构和模型是否一致
+// 有未执行或执行失败的迁移、模型字段对应的列不存在或者类型不匹配时返回错误，服务启动时调用
+func (r *Repository) CheckSchema(ctx context.Context) error {
+	states, err := r.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	var problems []error
+	for _, st := range states {
+		switch {
+		case st.Dirty:
+			problems = append(problems, fmt.Errorf("migration %d_%s is dirty", st.Version, st.Name))
+		case !st.Applied:
+			problems = append(problems, fmt.Errorf("migration %d_%s is not applied", st.Version, st.Name))
+		}
+	}
+
+	tx := r.db.WithContext(ctx)
+	for _, m := range _models {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(m); err != nil {
+			return err
+		}
+		for _, table := range r.schemaTables(stmt.Schema.Table) {
+			problems = append(problems, checkTable(tx, m, stmt.Schema, table)...)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("database schema does not match models, run migrate up: %w", errors.Join(problems...))
+	}
+	return nil
+}
+
+// schemaTables 模型对应的需要检查表结构的表，分表时包括模板表和所有分表
+func (r *Repository) schemaTables(table string) []string {
+	tables := []string{table}
+	if r.tables > 1 && slices.Contains(_splitTables, table) {
+		for i := 0; i < r.tables; i++ {
+			tables = append(tables, SplitTableName(table, i))
+		}
+	}
+	return tables
+}
+
+// checkTable 检查 table 的列和模型 m 是否一致，返回发现的问题
+func checkTable(tx *gorm.DB, m interface{}, sch *schema.Schema, table string) (problems []error) {
+	if !tx.Migrator().HasTable(table) {
+		return []error{fmt.Errorf("table %s does not exist", table)}
+	}
+	columnTypes, err := tx.Table(table).Migrator().ColumnTypes(m)
+	if err != nil {
+		return []error{fmt.Errorf("read columns of %s failed: %w", table, err)}
+	}
+	columns := make(map[string]string, len(columnTypes))
+	for _, ct := range columnTypes {
+		columns[ct.Name()] = ct.DatabaseTypeName()
+	}
+	for _, f := range sch.Fields {
+		if len(f.DBName) == 0 {
+			continue
+		}
+		dbType, ok := columns[f.DBName]
+		if !ok {
+			problems = append(problems, fmt.Errorf("column %s.%s (%s.%s) does not exist", table, f.DBName, sch.Name, f.Name))
+			continue
+		}
+		if !compatibleType(f.GORMDataType, dbType) {
+			problems = append(problems, fmt.Errorf("column %s.%s type %s does not match %s.%s (%s)", table, f.DBName, dbType, sch.Name, f.Name, f.GORMDataType))
+		}
+	}
+	return problems
+}
+
+// compatibleType 字段类型和数据库列类型是否兼容
+func compatibleType(dataType schema.DataType, dbType string) bool {
+	dbType = strings.TrimSpace(strings.TrimPrefix(strings.ToUpper(dbType), "UNSIGNED"))
+	if i := strings.IndexByte(dbType, '('); i >= 0 {
+		dbType = strings.TrimSpace(dbType[:i])
+	}
+	var allowed []string
+	switch dataType {
+	case schema.Int, schema.Uint:
+		allowed = []string{"TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT"}
+	case schema.Bool:
+		allowed = []string{"TINYINT", "BOOL", "BOOLEAN", "BIT"}
+	case schema.Float:
+		allowed = []string{"FLOAT", "DOUBLE", "DECIMAL"}
+	case schema.String:
+		allowed = []string{"CHAR", "VARCHAR", "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT", "ENUM"}
+	case schema.Time:
+		allowed = []string{"DATETIME", "TIMESTAMP", "DATE"}
+	default:
+		// 自定义类型不检查
+		return true
+	}
+	for _, t := range allowed {
+		if dbType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// withMigrateLock 在同一个连接上持有迁移锁执行 fn
+func (r *Repository) withMigrateLock(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Connection(func(tx *gorm.DB) error {
+		// 固定使用这个连接，每次调用都从新的语句开始
+		tx = tx.Session(&gorm.Session{NewDB: true})
+		if r.dialect.Lock != nil {
+			unlock, err := r.dialect.Lock(tx)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+		}
+		if err := tx.Exec(r.dialect.MigrationTableDDL).Error; err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// appliedMigrations 查询已执行（包括执行失败）的迁移
+func appliedMigrations(tx *gorm.DB) (map[int64]schemaMigration, error) {
+	var records []schemaMigration
+	if err := tx.Order("version").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]schemaMigration, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// runMigration 执行一个迁移版本的 up 或 down 脚本，涉及订单表、订单明细表的语句同时在所有分表上执行
+func (r *Repository) runMigration(tx *gorm.DB, mg Migration, up bool) error {
+	var dirty []schemaMigration
+	if err := tx.Where("dirty = ?", true).Limit(1).Find(&dirty).Error; err != nil {
+		return err
+	}
+	if len(dirty) > 0 {
+		return fmt.Errorf("migration %d_%s is dirty, fix the database and run migrate force", dirty[0].Version, dirty[0].Name)
+	}
+
+	script, direction := mg.Up, "up"
+	if !up {
+		script, direction = mg.Down, "down"
+	}
+	logger.Ctx(tx.Statement.Context).Info("Running migration",
+		zap.Int64("version", mg.Version), zap.String("name", mg.Name), zap.String("direction", direction))
+
+	// 先标记为 dirty，脚本执行到一半失败时保留标记
+	err := tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"dirty", "applied_at"}),
+	}).Create(&schemaMigration{Version: mg.Version, Name: mg.Name, Dirty: true, AppliedAt: time.Now()}).Error
+	if err != nil {
+		return err
+	}
+	for _, stmt := range splitStatements(script) {
+		for _, s := range append([]string{stmt}, r.splitTableStatements(stmt)...) {
+			if err := tx.Exec(s).Error; err != nil {
+				return fmt.Errorf("migration %d_%s %s failed: %w", mg.Version, mg.Name, direction, err)
+			}
+		}
+	}
+
+	if !up {
+		return tx.Delete(&schemaMigration{Version: mg.Version}).Error
+	}
+	return tx.Model(&schemaMigration{Version: mg.Version}).Update("dirty", false).Error
+}
+
+// splitStatements 按分号把脚本拆分成多条语句，忽略引号内的分号和 -- 注释
+func splitStatements(script string) []string {
+	var (
+		stmts []string
+		cur   strings.Builder
+		quote rune
+	)
+	lines := strings.Split(strings.ReplaceAll(script, "\r\n", "\n"), "\n")
+	for _, line := range lines {
+		if quote == 0 && strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		for _, r := range line {
+			switch {
+			case quote != 0:
+				if r == quote {
+					quote = 0
+				}
+			case r == '\'' || r == '"' || r == '`':
+				quote = r
+			case r == ';':
+				if s := strings.TrimSpace(cur.String()); len(s) > 0 {
+					stmts = append(stmts, s)
+				}
+				cur.Reset()
+				continue
+			}
+			cur.WriteRune(r)
+		}
+		cur.WriteByte('\n')
+	}
+	if s := strings.TrimSpace(cur.String()); len(s) > 0 {
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
+
+func hasVersion(migrations []Migration, version int64) bool {
+	for _, mg := range migrations {
+		if mg.Version == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,324 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"order_service/errno"
+	"order_service/logger"
+	"order_service/model"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// QueryOrder 查询订单（读从库），已经归档的订单从归档表中查询
+func (r *Repository) QueryOrder(ctx context.Context, orderId int64) (model.Order, error) {
+	var data model.Order
+	err := r.orders(r.reader(ctx)).
+		Where("order_id = ?", orderId).
+		First(&data).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return r.queryArchivedOrder(ctx, orderId)
+	}
+	return data, err
+}
+
+func (r *Repository) UpdateOrder(ctx context.Context, data model.Order) error {
+	return r.orders(r.db.WithContext(ctx)).
+		Where("order_id = ?", data.OrderId).
+		Updates(&data).Error
+}
+
+func (r *Repository) CreateOrder(ctx context.Context, data *model.Order) error {
+	return r.orders(r.db.WithContext(ctx)).
+		Save(data).Error
+}
+
+func (r *Repository) CreateOrderDetail(ctx context.Context, data *model.OrderDetail) error {
+	return r.details(r.db.WithContext(ctx)).
+		Save(data).Error
+}
+
+// CreateOrderWithTransation 创建订单事务处理，同时写入订单创建的事件
+func (r *Repository) CreateOrderWithTransation(ctx context.Context, order *model.Order, orderDetail *model.OrderDetail) error {
+	return r.db.WithContext(ctx).
+		Transaction(func(tx *gorm.DB) error {
+			// 在事务中执行一些 db 操作（从这里开始，您应该使用 'tx' 而不是 'db'）
+			if err := tx.Table(r.orderTable()).Create(order).Error; err != nil {
+				// 返回任何错误都会回滚事务
+				return err
+			}
+
+			if err := tx.Table(r.detailTable()).Create(orderDetail).Error; err != nil {
+				return err
+			}
+			// 返回 nil 提交事务
+			return createOrderEvent(tx, orderDetail.OrderId, orderDetail.UserId, "", orderDetail.Status,
+				model.StatusChange{Source: model.EventSourceCreate})
+		})
+}
+
+// UpdateOrderStatus 更新订单状态，状态有变化时在同一个事务中写入订单事件
+// order.FenceToken 大于 0 时同时写入 fencing token，已保存的 token 更大说明锁已经被别人拿走，拒绝本次更新
+func (r *Repository) UpdateOrderStatus(ctx context.Context, order *model.OrderDetail, change model.StatusChange) error {
+	updates := map[string]interface{}{
+		"status": order.Status,
+	}
+	if order.FenceToken > 0 {
+		updates["fence_token"] = order.FenceToken
+	}
+	// 更新订单状态，订单表中的状态在同一个事务中同步更新
+	var rowsAffected int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 锁住订单明细读取变化前的状态，订单不存在时下面的更新不会影响任何行
+		var current model.OrderDetail
+		err := r.details(tx).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("order_id", "user_id", "status").
+			Where("order_id = ?", order.OrderId).
+			Limit(1).
+			Find(&current).Error
+		if err != nil {
+			return err
+		}
+		if current.OrderId > 0 && !model.CanTransition(current.Status, order.Status) {
+			return errno.ErrInvalidStatus
+		}
+
+		// 指定操作的模型，这里操作的是 model.OrderDetail 表，根据 order_id 更新
+		query := r.details(tx).Where("order_id = ?", order.OrderId)
+		if order.FenceToken > 0 {
+			query = query.Where("fence_token <= ?", order.FenceToken)
+		}
+		result := query.Updates(updates)
+		rowsAffected = result.RowsAffected
+		if result.Error != nil || rowsAffected == 0 {
+			return result.Error
+		}
+		err = r.orders(tx).
+			Where("order_id = ?", order.OrderId).
+			Update("status", order.Status).Error
+		if err != nil || current.Status == order.Status {
+			return err
+		}
+		return createOrderEvent(tx, order.OrderId, current.UserId, current.Status, order.Status, change)
+	})
+
+	// 检查更新是否成功
+	if errors.Is(err, errno.ErrInvalidStatus) {
+		logger.Ctx(ctx).Warn("Invalid order status transition",
+			zap.Int64("order_id", order.OrderId), zap.String("status", order.Status))
+		return err
+	}
+	if err != nil {
+		logger.Ctx(ctx).Error("Failed to update order status", zap.Int64("order_id", order.OrderId), zap.Error(err))
+		return errno.ErrUpdateFailed
+	}
+
+	// 如果没有行被更新，返回错误
+	if rowsAffected == 0 {
+		if order.FenceToken > 0 && r.orderDetailExists(ctx, order.OrderId) {
+			logger.Ctx(ctx).Warn("Stale fencing token when updating order status",
+				zap.Int64("order_id", order.OrderId), zap.Int64("fence_token", order.FenceToken))
+			return errno.ErrStaleFenceToken
+		}
+		logger.Ctx(ctx).Warn("No rows affected when updating order status", zap.Int64("order_id", order.OrderId))
+		return errno.ErrOrderNotFound
+	}
+
+	// 更新成功，返回 nil
+	return nil
+}
+
+// orderDetailExists 订单明细是否存在
+func (r *Repository) orderDetailExists(ctx context.Context, orderId int64) bool {
+	var count int64
+	r.details(r.db.WithContext(ctx)).
+		Where("order_id = ?", orderId).
+		Count(&count)
+	return count > 0
+}
+
+// GetMinOrderIdAfterTime 获取指定时间后的最小订单ID
+func (r *Repository) GetMinOrderIdAfterTime(ctx context.Context, timestamp time.Time) (int64, error) {
+	var minID int64
+	err := r.orders(r.db.WithContext(ctx)).
+		Where("create_at > ?", timestamp).
+		Select("COALESCE(MIN(id), 0)").
+		Row().
+		Scan(&minID)
+	if err != nil {
+		return 0, err
+	}
+	return minID, nil
+}
+
+// GetShardParams 获取订单ID分片参数，只针对大于minOrderId的订单
+func (r *Repository) GetShardParams(ctx context.Context, minOrderId int64) ([]model.ShardParam, error) {
+	// 查询订单表中大于minOrderId的最小和最大ID
+	var minID, maxID int64
+	err := r.orders(r.db.WithContext(ctx)).
+		Where("id > ?", minOrderId).
+		Select("COALESCE(MIN(id), 0), COALESCE(MAX(id), 0)").
+		Row().
+		Scan(&minID, &maxID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 如果没有符合条件的订单，返回空分片
+	if minID == 0 && maxID == 0 {
+		return nil, nil
+	}
+
+	// 计算分片数量
+	shardCount := int64(10) // 将 shardCount 转换为 int64
+	totalRange := maxID - minID + 1
+	shardSize := totalRange / shardCount
+
+	var shardParams []model.ShardParam
+	for i := int64(0); i < shardCount; i++ {
+		startID := minID + i*shardSize
+		endID := minID + (i+1)*shardSize - 1
+		if i == shardCount-1 {
+			endID = maxID
+		}
+		shardParams = append(shardParams, model.ShardParam{
+			StartID: startID,
+			EndID:   endID,
+			ShardID: int(i),
+		})
+	}
+
+	return shardParams, nil
+}
+
+// QueryTimeoutOrdersByShard 按分片查询超时未支付的订单
+func (r *Repository) QueryTimeoutOrdersByShard(ctx context.Context, startID, endID int64, timeoutTime time.Time) ([]model.OrderDetail, error) {
+	var orders []model.OrderDetail
+	err := r.details(r.db.WithContext(ctx)).
+		Where("id BETWEEN ? AND ? AND status IN ? AND create_at < ?", startID, endID,
+			[]string{model.OrderStatusPending, model.OrderStatusUnpaid}, timeoutTime).
+		Find(&orders).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// QueryOrderList 分页查询用户的订单列表，返回当前页数据和总数（读从库）
+func (r *Repository) QueryOrderList(ctx context.Context, userId int64, offset, limit int) ([]model.Order, int64, error) {
+	var (
+		total  int64
+		orders []model.Order
+	)
+	query := r.orders(r.reader(ctx)).
+		Where("user_id = ?", userId)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.
+		Order("id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&orders).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return orders, total, nil
+}
+
+// SearchOrders 按条件分页查询订单，按创建时间和订单号倒序，返回当前页数据和总数（读从库）
+// 只查询订单表，已经归档的订单不在结果中
+func (r *Repository) SearchOrders(ctx context.Context, filter model.OrderFilter, offset, limit int) ([]model.Order, int64, error) {
+	var (
+		total  int64
+		orders []model.Order
+	)
+	query := r.orders(r.reader(ctx))
+	if filter.UserId > 0 {
+		query = query.Where("user_id = ?", filter.UserId)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		query = query.Where("create_at >= ?", filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		query = query.Where("create_at < ?", filter.CreatedBefore)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.
+		Order("create_at DESC, order_id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&orders).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return orders, total, nil
+}
+
+// QueryOrderDetail 查询订单的商品明细（读从库），已经归档的订单从归档表中查询
+func (r *Repository) QueryOrderDetail(ctx context.Context, orderId int64) (model.OrderDetail, error) {
+	var data model.OrderDetail
+	err := r.details(r.reader(ctx)).
+		Where("order_id = ?", orderId).
+		First(&data).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return r.queryArchivedOrderDetail(ctx, orderId)
+	}
+	return data, err
+}
+
+// QueryOrderDetails 批量查询多个订单的商品明细（读从库），原表中找不到的再查询归档表
+func (r *Repository) QueryOrderDetails(ctx context.Context, orderIds []int64) ([]model.OrderDetail, error) {
+	var details []model.OrderDetail
+	if len(orderIds) == 0 {
+		return details, nil
+	}
+	err := r.details(r.reader(ctx)).
+		Where("order_id IN ?", orderIds).
+		Find(&details).Error
+	if err != nil {
+		return nil, err
+	}
+	found := make([]int64, 0, len(details))
+	for _, d := range details {
+		found = append(found, d.OrderId)
+	}
+	missing := missingOrderIds(orderIds, found)
+	if len(missing) == 0 {
+		return details, nil
+	}
+	archived, err := r.queryArchivedOrderDetails(ctx, missing)
+	return append(details, archived...), err
+}
+
+// QueryExistingOrderIds 查询 orderIds 中存在的订单ID，包括已经归档、已经删除的订单
+func (r *Repository) QueryExistingOrderIds(ctx context.Context, orderIds []int64) ([]int64, error) {
+	var ids []int64
+	if len(orderIds) == 0 {
+		return ids, nil
+	}
+	// 已经软删除的订单也算存在
+	err := r.orders(r.db.WithContext(ctx)).
+		Unscoped().
+		Where("order_id IN ?", orderIds).
+		Pluck("order_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	missing := missingOrderIds(orderIds, ids)
+	if len(missing) == 0 {
+		return ids, nil
+	}
+	archived, err := r.queryArchivedOrderIds(ctx, missing)
+	return append(ids, archived...), err
+}
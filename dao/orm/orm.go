@@ -0,0 +1,120 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 基于 gorm 的订单存储实现，MySQL 和 SQLite 后端共用
+// 查询只使用两种数据库都支持的语法，数据库之间的差异（连接方式、迁移脚本、迁移锁）由 Dialect 描述
+
+// Dialect 数据库方言
+type Dialect struct {
+	Name string // 数据库名称，用于日志和监控指标
+
+	// Migrations 迁移脚本目录，文件名格式为 {版本号}_{名称}.up.sql / .down.sql
+	Migrations fs.FS
+	// MigrationTableDDL 创建 schema_migrations 表的语句
+	MigrationTableDDL string
+	// Lock 在 tx 所在的连接上加迁移锁，返回解锁函数，为 nil 时不加锁
+	Lock func(tx *gorm.DB) (unlock func(), err error)
+	// ReplicaLag 查询从库的复制延迟，复制中断时返回错误，为 nil 时不检查，从库始终不使用
+	ReplicaLag func(db *gorm.DB) (time.Duration, error)
+	// CopyTable 按 table 的表结构（包括索引）创建第 n 张分表，分表已经存在时不做任何操作，见 split.go
+	CopyTable func(tx *gorm.DB, table string, n int) error
+}
+
+// conn 一个库的连接，分表时同一个库的所有分表共用
+type conn struct {
+	db      *gorm.DB
+	dialect *Dialect
+
+	replicas []*Replica    // 只读从库，见 replica.go
+	next     atomic.Uint64 // 轮询从库的计数
+	maxLag   atomic.Int64  // 允许的最大复制延迟
+	done     chan struct{} // Close 时关闭，停止从库延迟检查
+	close    sync.Once
+
+	tables int   // 订单表和订单明细表的分表数量，1 表示不分表，见 split.go
+	shift  uint8 // 计算分表序号前 key 右移的位数
+}
+
+// Repository 订单存储，分表时每张分表对应一个 Repository
+type Repository struct {
+	*conn
+	table int // 分表序号
+}
+
+// New 使用已经打开的 gorm 连接（主库）创建订单存储
+func New(db *gorm.DB, dialect *Dialect) (*Repository, error) {
+	if err := registerWriteCallbacks(db); err != nil {
+		return nil, err
+	}
+	if err := registerAuditCallbacks(db); err != nil {
+		return nil, err
+	}
+	return &Repository{conn: &conn{db: db, dialect: dialect, done: make(chan struct{}), tables: 1}}, nil
+}
+
+// Name 数据库名称
+func (r *Repository) Name() string {
+	return r.dialect.Name
+}
+
+// SQLDB 返回主库底层的 database/sql 连接池（用于采集连接池指标）
+func (r *Repository) SQLDB() (*sql.DB, error) {
+	return r.db.DB()
+}
+
+// SetConnPool 调整主库和所有从库的连接池大小
+func (r *Repository) SetConnPool(maxIdle, maxOpen int) error {
+	dbs := []*gorm.DB{r.db}
+	for _, rep := range r.replicas {
+		dbs = append(dbs, rep.db)
+	}
+	for _, db := range dbs {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		sqlDB.SetMaxIdleConns(maxIdle)
+		sqlDB.SetMaxOpenConns(maxOpen)
+	}
+	return nil
+}
+
+// Ping 检查数据库连接是否可用（供健康检查使用）
+func (r *Repository) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Close 关闭主库和从库的连接，同一个库的分表共用连接，只关闭一次
+func (r *Repository) Close() error {
+	var errs []error
+	r.close.Do(func() {
+		close(r.done)
+		dbs := []*gorm.DB{r.db}
+		for _, rep := range r.replicas {
+			dbs = append(dbs, rep.db)
+		}
+		for _, db := range dbs {
+			sqlDB, err := db.DB()
+			if err == nil {
+				err = sqlDB.Close()
+			}
+			errs = append(errs, err)
+		}
+	})
+	return errors.Join(errs...)
+}
@@ -0,0 +1,151 @@
+package orm
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"order_service/metrics"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 读写分离
+// 订单列表和订单详情的查询（QueryOrder、QueryOrderList、QueryOrderDetail、QueryOrderDetails）读从库，
+// 其他查询和所有写操作都走主库。以下情况这些查询也读主库：
+//   - 不是 RPC 请求：只有经过 WithRequest 标记的 ctx 才会读从库，超时扫描、消息消费者、对账等后台任务始终读主库
+//   - ctx 经过 WithPrimary 标记，例如更新前在锁内检查订单状态
+//   - 同一个请求中已经写过数据库，从库可能还没有同步到刚写入的数据
+//   - 没有可用的从库：复制延迟超过 max_replica_lag、复制中断或者连接失败
+
+type requestKey struct{}
+
+type primaryKey struct{}
+
+// request 一个请求的读写状态
+type request struct {
+	wrote atomic.Bool // 是否已经写过数据库
+}
+
+// WithRequest 标记 ctx 属于一个 RPC 请求，请求中的订单查询可以读从库
+func WithRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestKey{}, new(request))
+}
+
+// WithPrimary 标记 ctx 中的查询都读主库
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// markWrite 记录请求已经写过数据库，之后的查询都读主库
+func markWrite(db *gorm.DB) {
+	if db.Statement.Context == nil {
+		return
+	}
+	if req, ok := db.Statement.Context.Value(requestKey{}).(*request); ok {
+		req.wrote.Store(true)
+	}
+}
+
+// readFromPrimary 是否必须读主库
+func readFromPrimary(ctx context.Context) bool {
+	if primary, _ := ctx.Value(primaryKey{}).(bool); primary {
+		return true
+	}
+	req, ok := ctx.Value(requestKey{}).(*request)
+	return !ok || req.wrote.Load()
+}
+
+// registerWriteCallbacks 在主库的写操作（包括 Exec）之后记录请求已经写过数据库
+func registerWriteCallbacks(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Register("order:mark_write", markWrite); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("order:mark_write", markWrite); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("order:mark_write", markWrite); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register("order:mark_write", markWrite)
+}
+
+// Replica 只读从库
+type Replica struct {
+	Name string // 从库地址，用于日志和监控指标
+
+	db      *gorm.DB
+	healthy atomic.Bool // 最近一次检查时复制延迟是否在允许范围内
+}
+
+// AddReplica 添加从库，从库在第一次延迟检查通过前不会被使用
+func (r *Repository) AddReplica(name string, db *gorm.DB) {
+	r.replicas = append(r.replicas, &Replica{Name: name, db: db})
+}
+
+// SetMaxReplicaLag 设置允许的最大复制延迟，超过时读主库
+func (r *Repository) SetMaxReplicaLag(lag time.Duration) {
+	r.maxLag.Store(int64(lag))
+}
+
+// reader 返回读订单数据使用的连接，有多个可用从库时轮询
+func (r *Repository) reader(ctx context.Context) *gorm.DB {
+	if len(r.replicas) > 0 && !readFromPrimary(ctx) {
+		n := uint64(len(r.replicas))
+		start := r.next.Add(1)
+		for i := uint64(0); i < n; i++ {
+			if rep := r.replicas[(start+i)%n]; rep.healthy.Load() {
+				return rep.db.WithContext(ctx)
+			}
+		}
+	}
+	return r.db.WithContext(ctx)
+}
+
+// StartReplicaMonitor 每隔 interval 检查一次从库的复制延迟，Close 时停止
+// 没有从库或者方言不支持检查复制延迟时不启动
+func (r *Repository) StartReplicaMonitor(interval time.Duration) {
+	if len(r.replicas) == 0 || r.dialect.ReplicaLag == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			r.checkReplicas(interval)
+			select {
+			case <-r.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// checkReplicas 检查所有从库的复制延迟，更新从库是否可用
+func (r *Repository) checkReplicas(timeout time.Duration) {
+	maxLag := time.Duration(r.maxLag.Load())
+	for _, rep := range r.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		lag, err := r.dialect.ReplicaLag(rep.db.WithContext(ctx))
+		cancel()
+
+		healthy := err == nil && lag <= maxLag
+		if rep.healthy.Swap(healthy) != healthy {
+			zap.L().Warn("replica availability changed",
+				zap.String("database", r.dialect.Name),
+				zap.String("replica", rep.Name),
+				zap.Bool("available", healthy),
+				zap.Duration("lag", lag),
+				zap.Error(err))
+		}
+		metrics.ReplicaLag.WithLabelValues(r.dialect.Name, rep.Name).Set(lag.Seconds())
+		if healthy {
+			metrics.ReplicaAvailable.WithLabelValues(r.dialect.Name, rep.Name).Set(1)
+		} else {
+			metrics.ReplicaAvailable.WithLabelValues(r.dialect.Name, rep.Name).Set(0)
+		}
+	}
+}
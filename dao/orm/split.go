@@ -0,0 +1,143 @@
+package orm
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"order_service/model"
+
+	"gorm.io/gorm"
+)
+
+// 分表
+// 订单表和订单明细表在每个库中分成 n 张表（xx_order_0 ~ xx_order_{n-1}），其他表不分表。
+// 分表序号 = (key >> shift) 的低 log2(n) 位，key 为用户ID或者订单号（基因和用户ID相同），
+// shift 为分库占用的位数，所以同一个用户的订单总是在同一个库的同一张表中。
+//
+// 不带序号的 xx_order、xx_order_detail 作为表结构模板保留，不保存数据：
+//   - 迁移脚本中涉及这两张表的语句，对每张分表再执行一次（表名和索引名加上 _{n} 后缀），
+//     所以迁移脚本中的表名和索引名都要用反引号
+//   - 已经执行过迁移的库开启分表时，按模板表的当前结构创建分表（Dialect.CopyTable）
+//
+// 开启分表前写入模板表的数据不会自动迁移，需要按用户ID迁移到对应的分表并重新生成订单号。
+
+// _splitIdentifier 迁移脚本中需要加分表后缀的标识符：订单表、订单明细表和索引
+var _splitIdentifier = regexp.MustCompile("`(xx_order|xx_order_detail|idx_[0-9A-Za-z_]+|uk_[0-9A-Za-z_]+)`")
+
+// _splitTables 分表的模板表
+var _splitTables = []string{model.Order{}.TableName(), model.OrderDetail{}.TableName()}
+
+// SplitTables 把订单表和订单明细表分成 n 张表，返回每张分表对应的 Repository，n 为 1 时只返回 r
+// n 必须是 2 的幂，shift 为计算分表序号前 key 右移的位数；返回的 Repository 共用 r 的连接
+func (r *Repository) SplitTables(n int, shift uint8) []*Repository {
+	if n <= 1 {
+		return []*Repository{r}
+	}
+	r.tables, r.shift = n, shift
+	list := make([]*Repository, n)
+	for i := range list {
+		list[i] = &Repository{conn: r.conn, table: i}
+	}
+	return list
+}
+
+// Table 订单表的表名，分表时为当前分表的表名
+func (r *Repository) Table() string {
+	return r.orderTable()
+}
+
+// orderTable 订单表的表名
+func (r *Repository) orderTable() string {
+	return r.splitTable(model.Order{}.TableName())
+}
+
+// detailTable 订单明细表的表名
+func (r *Repository) detailTable() string {
+	return r.splitTable(model.OrderDetail{}.TableName())
+}
+
+func (r *Repository) splitTable(table string) string {
+	if r.tables <= 1 {
+		return table
+	}
+	return SplitTableName(table, r.table)
+}
+
+// orders 订单表的查询
+func (r *Repository) orders(db *gorm.DB) *gorm.DB {
+	return db.Model(&model.Order{}).Table(r.orderTable())
+}
+
+// details 订单明细表的查询
+func (r *Repository) details(db *gorm.DB) *gorm.DB {
+	return db.Model(&model.OrderDetail{}).Table(r.detailTable())
+}
+
+// ownedBy 只保留 column 中的订单号属于当前分表的记录，用于不分表的表（例如库存台账）和分表关联查询
+func (r *Repository) ownedBy(db *gorm.DB, column string) *gorm.DB {
+	if r.tables <= 1 {
+		return db
+	}
+	return db.Where(fmt.Sprintf("(%s >> ?) & ? = ?", column), r.shift, r.tables-1, r.table)
+}
+
+// SplitTableName 第 n 张分表的表名
+func SplitTableName(table string, n int) string {
+	return table + "_" + strconv.Itoa(n)
+}
+
+// SplitDDL 把模板表的 DDL 改写为第 n 张分表的 DDL，表名和索引名加上 _{n} 后缀
+func SplitDDL(ddl string, n int) string {
+	return _splitIdentifier.ReplaceAllStringFunc(ddl, func(s string) string {
+		return "`" + SplitTableName(s[1:len(s)-1], n) + "`"
+	})
+}
+
+// splitTableStatements 迁移语句在每张分表上执行的版本，语句不涉及订单表、订单明细表或者没有分表时返回 nil
+func (r *Repository) splitTableStatements(stmt string) []string {
+	if r.tables <= 1 || !touchesSplitTables(stmt) {
+		return nil
+	}
+	list := make([]string, r.tables)
+	for i := range list {
+		list[i] = SplitDDL(stmt, i)
+	}
+	return list
+}
+
+func touchesSplitTables(stmt string) bool {
+	for _, m := range _splitIdentifier.FindAllStringSubmatch(stmt, -1) {
+		for _, t := range _splitTables {
+			if m[1] == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// createSplitTables 模板表已经存在、分表还不存在时按模板表的结构创建分表
+// 在执行未执行的迁移之前调用，之后的迁移会同时修改模板表和分表
+func (r *Repository) createSplitTables(tx *gorm.DB) error {
+	if r.tables <= 1 {
+		return nil
+	}
+	if r.dialect.CopyTable == nil {
+		return fmt.Errorf("%s does not support splitting tables", r.dialect.Name)
+	}
+	for _, table := range _splitTables {
+		if !tx.Migrator().HasTable(table) {
+			continue
+		}
+		for i := 0; i < r.tables; i++ {
+			if tx.Migrator().HasTable(SplitTableName(table, i)) {
+				continue
+			}
+			if err := r.dialect.CopyTable(tx, table, i); err != nil {
+				return fmt.Errorf("create %s failed: %w", SplitTableName(table, i), err)
+			}
+		}
+	}
+	return nil
+}
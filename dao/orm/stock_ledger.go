@@ -0,0 +1,131 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"order_service/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecordStockDeduction 记录一笔库存扣减，重复写入时忽略
+func (r *Repository) RecordStockDeduction(ctx context.Context, orderId, goodsId, num int64) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.StockLedger{
+			OrderId: orderId,
+			GoodsId: goodsId,
+			Num:     num,
+			Status:  model.LedgerStatusDeducted,
+		}).Error
+}
+
+// ClaimStockRollback 抢占一笔库存回滚，返回 false 表示已经回滚过或者正在回滚
+// 没有扣减记录的订单（台账上线前创建的订单）直接写入一条回滚中的记录
+func (r *Repository) ClaimStockRollback(ctx context.Context, orderId, goodsId, num int64, source string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.StockLedger{}).
+		Where("order_id = ? AND goods_id = ? AND status = ?", orderId, goodsId, model.LedgerStatusDeducted).
+		Updates(map[string]interface{}{
+			"status": model.LedgerStatusRollingBack,
+			"source": source,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Where("order_id = ? AND goods_id = ?", orderId, goodsId).
+		First(&model.StockLedger{}).Error
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+	result = r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.StockLedger{
+			OrderId: orderId,
+			GoodsId: goodsId,
+			Num:     num,
+			Status:  model.LedgerStatusRollingBack,
+			Source:  source,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// FinishStockRollback 回滚结束，成功时标记为已回滚，失败时恢复为已扣减，下次可以重新回滚
+func (r *Repository) FinishStockRollback(ctx context.Context, orderId, goodsId int64, ok bool) error {
+	status := model.LedgerStatusDeducted
+	if ok {
+		status = model.LedgerStatusRolledBack
+	}
+	return r.db.WithContext(ctx).
+		Model(&model.StockLedger{}).
+		Where("order_id = ? AND goods_id = ? AND status = ?", orderId, goodsId, model.LedgerStatusRollingBack).
+		Update("status", status).Error
+}
+
+// _maxDriftsPerKind 每种不一致最多返回的记录数
+const _maxDriftsPerKind = 1000
+
+// QueryLedgerDrifts 对比台账和订单明细，查询不一致的记录
+// since 之前创建的订单不参与对比；grace 内的记录可能还在处理中，也不参与对比
+func (r *Repository) QueryLedgerDrifts(ctx context.Context, since time.Time, grace time.Duration) ([]model.LedgerDrift, error) {
+	before := time.Now().Add(-grace)
+	closed := []string{model.OrderStatusTimeout, model.OrderStatusCancelled}
+	// 台账不分表，从台账出发的 LEFT JOIN 只对比属于当前分表的订单
+	join := "JOIN " + r.detailTable() + " AS d ON d.order_id = l.order_id AND d.goods_id = l.goods_id"
+	queries := []struct {
+		kind  string
+		build func(tx *gorm.DB) *gorm.DB
+	}{
+		{model.DriftMissingRollback, func(tx *gorm.DB) *gorm.DB {
+			return tx.Table("xx_stock_ledger AS l").
+				Joins(join).
+				Where("l.status = ? AND d.status IN ? AND d.update_at < ?", model.LedgerStatusDeducted, closed, before)
+		}},
+		{model.DriftUnexpectedRollback, func(tx *gorm.DB) *gorm.DB {
+			return tx.Table("xx_stock_ledger AS l").
+				Joins(join).
+				Where("l.status = ? AND d.status NOT IN ? AND l.create_at >= ?", model.LedgerStatusRolledBack, closed, since)
+		}},
+		{model.DriftStuckRollback, func(tx *gorm.DB) *gorm.DB {
+			return r.ownedBy(tx.Table("xx_stock_ledger AS l"), "l.order_id").
+				Joins("LEFT "+join).
+				Where("l.status = ? AND l.update_at < ?", model.LedgerStatusRollingBack, before)
+		}},
+		{model.DriftOrphanDeduction, func(tx *gorm.DB) *gorm.DB {
+			return r.ownedBy(tx.Table("xx_stock_ledger AS l"), "l.order_id").
+				Joins("LEFT "+join).
+				Where("l.status = ? AND d.id IS NULL AND l.create_at BETWEEN ? AND ?", model.LedgerStatusDeducted, since, before)
+		}},
+		{model.DriftMissingLedger, func(tx *gorm.DB) *gorm.DB {
+			return tx.Table(r.detailTable()+" AS d").
+				Joins("LEFT JOIN xx_stock_ledger AS l ON l.order_id = d.order_id AND l.goods_id = d.goods_id").
+				Where("l.id IS NULL AND d.create_at BETWEEN ? AND ?", since, before)
+		}},
+	}
+
+	var drifts []model.LedgerDrift
+	for _, q := range queries {
+		var rows []model.LedgerDrift
+		err := q.build(r.db.WithContext(ctx)).
+			Select("? AS kind, COALESCE(l.order_id, d.order_id) AS order_id, COALESCE(l.goods_id, d.goods_id) AS goods_id, "+
+				"COALESCE(l.status, '') AS ledger_status, COALESCE(d.status, '') AS order_status", q.kind).
+			Limit(_maxDriftsPerKind).
+			Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, rows...)
+	}
+	return drifts, nil
+}
@@ -0,0 +1,67 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 冷订单归档任务的状态，所有实例共用
+// 暂停标记保存在 redis 中，暂停后所有实例都不再归档，直到恢复
+const archiveKey = "order:archive" // hash：paused、last_run_at、last_archived、last_error
+
+// ArchiveState 归档任务的状态
+type ArchiveState struct {
+	Paused       bool
+	LastRunAt    time.Time // 最近一次执行的结束时间，没有执行过时为零值
+	LastArchived int64     // 最近一次执行归档的订单数
+	LastError    string    // 最近一次执行失败的原因
+}
+
+// SetArchivePaused 暂停或恢复归档任务
+func SetArchivePaused(ctx context.Context, paused bool) error {
+	return Client().HSet(ctx, archiveKey, "paused", strconv.FormatBool(paused)).Err()
+}
+
+// ArchivePaused 归档任务是否已经暂停
+func ArchivePaused(ctx context.Context) (bool, error) {
+	v, err := Client().HGet(ctx, archiveKey, "paused").Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(v)
+}
+
+// GetArchiveState 查询归档任务的状态
+func GetArchiveState(ctx context.Context) (ArchiveState, error) {
+	var st ArchiveState
+	vals, err := Client().HGetAll(ctx, archiveKey).Result()
+	if err != nil {
+		return st, err
+	}
+	st.Paused, _ = strconv.ParseBool(vals["paused"])
+	if sec, _ := strconv.ParseInt(vals["last_run_at"], 10, 64); sec > 0 {
+		st.LastRunAt = time.Unix(sec, 0)
+	}
+	st.LastArchived, _ = strconv.ParseInt(vals["last_archived"], 10, 64)
+	st.LastError = vals["last_error"]
+	return st, nil
+}
+
+// SaveArchiveRun 记录一次归档的结果
+func SaveArchiveRun(ctx context.Context, at time.Time, archived int, runErr error) error {
+	var errText string
+	if runErr != nil {
+		errText = runErr.Error()
+	}
+	return Client().HSet(ctx, archiveKey,
+		"last_run_at", at.Unix(),
+		"last_archived", archived,
+		"last_error", errText,
+	).Err()
+}
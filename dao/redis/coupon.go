@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 优惠券单次使用控制
+// 每张券在 redis 中对应一个 key，值为使用该券的订单ID，SETNX 成功的订单才能使用，
+// 数据库中的状态是最终记录，redis 挡住并发的重复使用。
+
+func couponKey(code string) string {
+	return fmt.Sprintf("coupon:claim:%s", code)
+}
+
+// releaseScript 只有 key 的值等于订单ID 时才删除，避免释放别的订单占用的券
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// ClaimCoupon 为订单占用优惠券，返回 false 表示券已经被其他订单占用
+// 同一个订单重复占用返回 true
+func ClaimCoupon(ctx context.Context, code string, orderId int64, ttl time.Duration) (bool, error) {
+	key, val := couponKey(code), strconv.FormatInt(orderId, 10)
+	ok, err := Client().SetNX(ctx, key, val, ttl).Result()
+	if err != nil || ok {
+		return ok, err
+	}
+	cur, err := Client().Get(ctx, key).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	return cur == val, err
+}
+
+// ReleaseCouponClaim 释放订单对优惠券的占用
+func ReleaseCouponClaim(ctx context.Context, code string, orderId int64) error {
+	return releaseScript.Run(ctx, Client(), []string{couponKey(code)}, strconv.FormatInt(orderId, 10)).Err()
+}
@@ -0,0 +1,123 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"order_service/config"
+	"order_service/errno"
+	"order_service/logger"
+
+	"github.com/go-redsync/redsync/v4"
+	"go.uber.org/zap"
+)
+
+// 订单分布式锁
+// 超时消息消费者、超时扫描任务、状态更新接口（以及后续的支付回调）都可能同时修改同一个订单，
+// 修改订单状态或回滚库存前都要先拿到订单锁。
+//
+// 锁可能因为 GC 停顿、网络抖动等原因在持有者不知情的情况下过期，
+// 所以每次加锁都会分配一个递增的 fencing token，和订单状态一起写入数据库，
+// 数据库只接受不小于已保存 token 的写入，过期的持有者的写入会被拒绝。
+
+// fenceKey 全局递增的 fencing token，所有订单共用，不设置过期时间
+const fenceKey = "order:fence"
+
+func orderLockKey(orderId int64) string {
+	return fmt.Sprintf("lock:order:%d", orderId)
+}
+
+// WithOrderLock 持有订单锁执行 fn，token 为本次加锁的 fencing token
+// fn 执行期间会定期续期，续期失败（锁已丢失）时取消 fn 的 ctx
+func WithOrderLock(ctx context.Context, orderId int64, fn func(ctx context.Context, token int64) error) error {
+	cfg := config.Get().LockConfig
+	m := Rs.NewMutex(orderLockKey(orderId),
+		redsync.WithExpiry(cfg.Expiry),
+		redsync.WithTries(cfg.Tries),
+		redsync.WithRetryDelay(cfg.RetryDelay),
+	)
+	if err := m.LockContext(ctx); err != nil {
+		var taken *redsync.ErrTaken
+		if errors.Is(err, redsync.ErrFailed) || errors.As(err, &taken) {
+			return errno.ErrOrderLocked
+		}
+		return fmt.Errorf("lock order %d failed: %w", orderId, err)
+	}
+	// 解锁不受 ctx 取消的影响
+	defer func() {
+		if ok, err := m.UnlockContext(context.WithoutCancel(ctx)); !ok || err != nil {
+			logger.Ctx(ctx).Warn("unlock order failed", zap.Int64("order_id", orderId), zap.Error(err))
+		}
+	}()
+
+	token, err := Client().Incr(ctx, fenceKey).Result()
+	if err != nil {
+		return fmt.Errorf("get fencing token failed: %w", err)
+	}
+
+	lockCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	done := make(chan struct{})
+	defer close(done)
+	go keepAlive(lockCtx, m, cfg.Expiry/2, done, cancel, errno.ErrOrderLockLost)
+
+	return fn(lockCtx, token)
+}
+
+func jobLockKey(name string) string {
+	return fmt.Sprintf("lock:job:%s", name)
+}
+
+// WithJobLock 持有后台任务锁执行 fn，保证多个实例中同一时间只有一个在执行任务
+// 锁已经被其他实例持有时不等待，直接返回 errno.ErrJobRunning
+func WithJobLock(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	cfg := config.Get().LockConfig
+	m := Rs.NewMutex(jobLockKey(name),
+		redsync.WithExpiry(cfg.Expiry),
+		redsync.WithTries(1),
+	)
+	if err := m.LockContext(ctx); err != nil {
+		var taken *redsync.ErrTaken
+		if errors.Is(err, redsync.ErrFailed) || errors.As(err, &taken) {
+			return errno.ErrJobRunning
+		}
+		return fmt.Errorf("lock job %s failed: %w", name, err)
+	}
+	defer func() {
+		if ok, err := m.UnlockContext(context.WithoutCancel(ctx)); !ok || err != nil {
+			logger.Ctx(ctx).Warn("unlock job failed", zap.String("job", name), zap.Error(err))
+		}
+	}()
+
+	lockCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	done := make(chan struct{})
+	defer close(done)
+	go keepAlive(lockCtx, m, cfg.Expiry/2, done, cancel, errno.ErrJobLockLost)
+
+	return fn(lockCtx)
+}
+
+// keepAlive 每隔 interval 续期一次，直到 done 被关闭，续期失败时以 lost 为原因取消 ctx
+func keepAlive(ctx context.Context, m *redsync.Mutex, interval time.Duration, done <-chan struct{}, cancel context.CancelCauseFunc, lost error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ok, err := m.ExtendContext(ctx)
+			if ok && err == nil {
+				continue
+			}
+			logger.Ctx(ctx).Warn("extend lock failed", zap.String("lock", m.Name()), zap.Error(err))
+			cancel(lost)
+			return
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// 滑动窗口限流
+// 每个限流对象在 redis 中对应一个有序集合，成员为一次请求，分数为请求时间（毫秒），
+// 统计窗口内的成员数判断是否超过限制，所有实例共享计数。
+
+func rateLimitKey(scope string) string {
+	return fmt.Sprintf("ratelimit:%s", scope)
+}
+
+// slidingWindowScript 窗口内的请求数小于 limit 时记录本次请求并返回 0，
+// 否则返回最早的请求移出窗口还需要的毫秒数
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+if redis.call("ZCARD", KEYS[1]) < tonumber(ARGV[3]) then
+	redis.call("ZADD", KEYS[1], now, ARGV[4])
+	redis.call("PEXPIRE", KEYS[1], window)
+	return 0
+end
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+return tonumber(oldest[2]) + window - now
+`)
+
+// AllowInWindow scope 在 window 内的请求数不超过 limit 时记录本次请求并返回 0，
+// 超过时不记录，返回需要等待的时间
+// 请求时间使用本机时间，各实例的时钟误差会体现在窗口边界上
+func AllowInWindow(ctx context.Context, scope string, limit int, window time.Duration) (time.Duration, error) {
+	wait, err := slidingWindowScript.Run(ctx, Client(), []string{rateLimitKey(scope)},
+		time.Now().UnixMilli(), window.Milliseconds(), limit, uuid.NewString()).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
@@ -2,36 +2,93 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"order_service/config"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/go-redsync/redsync/v4"
+	redsyncredis "github.com/go-redsync/redsync/v4/redis"
 	"github.com/go-redsync/redsync/v4/redis/goredis/v8"
+	"go.uber.org/zap"
 )
 
 // redsync -> https://github.com/go-redsync/redsync
 
 var (
-	rc *redis.Client
+	rc atomic.Pointer[redis.Client] // 配置热加载时会整体替换
 	Rs *redsync.Redsync
 )
 
 func Init(cfg *config.RedisConfig) error {
-	rc = redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		Password: cfg.Password, // 密码
-		DB:       cfg.DB,       // 数据库
-		PoolSize: cfg.PoolSize, // 连接池大小
-	})
-	err := rc.Ping(context.Background()).Err()
+	c, err := newClient(cfg)
 	if err != nil {
 		return err
 	}
-	pool := goredis.NewPool(rc) // or, pool := redigo.NewPool(...)
+	rc.Store(c)
 
 	// Create an instance of redisync to be used to obtain a mutual exclusion
 	// lock.
-	Rs = redsync.New(pool)
+	Rs = redsync.New(currentPool{})
 	return nil
 }
+
+// Client 返回当前使用的 redis 客户端
+func Client() *redis.Client {
+	return rc.Load()
+}
+
+func newClient(cfg *config.RedisConfig) (*redis.Client, error) {
+	c := redis.NewClient(&redis.Options{
+		Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Password:     cfg.Password,     // 密码
+		DB:           cfg.DB,           // 数据库
+		PoolSize:     cfg.PoolSize,     // 连接池大小
+		MinIdleConns: cfg.MinIdleConns, // 最小空闲连接数
+	})
+	if err := c.Ping(context.Background()).Err(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload 配置热加载时重建 redis 客户端
+// go-redis 的连接池大小不能动态修改，配置变化时创建新客户端替换旧客户端，
+// 旧客户端延迟关闭，保证正在执行的命令能正常完成
+func Reload(old, cur *config.RedisConfig) error {
+	if *old == *cur {
+		return nil
+	}
+	c, err := newClient(cur)
+	if err != nil {
+		return err
+	}
+	prev := rc.Swap(c)
+	if prev != nil {
+		time.AfterFunc(30*time.Second, func() {
+			if err := prev.Close(); err != nil {
+				zap.L().Warn("close old redis client failed", zap.Error(err))
+			}
+		})
+	}
+	return nil
+}
+
+// currentPool 每次都从当前的 redis 客户端获取连接，客户端替换后 Rs 无需重建
+type currentPool struct{}
+
+func (currentPool) Get(ctx context.Context) (redsyncredis.Conn, error) {
+	return goredis.NewPool(Client()).Get(ctx)
+}
+
+// Ping 检查Redis连接是否可用（供健康检查使用）
+func Ping(ctx context.Context) error {
+	c := Client()
+	if c == nil {
+		return errors.New("redis not initialized")
+	}
+	return c.Ping(ctx).Err()
+}
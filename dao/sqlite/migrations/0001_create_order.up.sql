@@ -0,0 +1,46 @@
+-- 订单表和订单商品表
+CREATE TABLE IF NOT EXISTS `xx_order`(
+    `id` INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    `create_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `create_by` VARCHAR(64) NOT NULL DEFAULT '',
+    `update_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `update_by` VARCHAR(64) NOT NULL DEFAULT '',
+    `version` INTEGER NOT NULL DEFAULT 0,
+    `is_del` INTEGER NOT NULL DEFAULT 0,
+    `user_id` INTEGER NOT NULL,
+    `order_id` INTEGER NOT NULL,
+    `pay_amount` INTEGER NOT NULL DEFAULT 0,
+    `receive_address` VARCHAR(128) NOT NULL DEFAULT '',
+    `receive_name` VARCHAR(128) NOT NULL DEFAULT '',
+    `receive_phone` VARCHAR(11) NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS `idx_xx_order_user_id` ON `xx_order` (user_id);
+CREATE INDEX IF NOT EXISTS `idx_xx_order_order_id` ON `xx_order` (order_id);
+CREATE INDEX IF NOT EXISTS `idx_xx_order_is_del` ON `xx_order` (is_del);
+
+CREATE TABLE IF NOT EXISTS `xx_order_detail`(
+    `id` INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    `create_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `create_by` VARCHAR(64) NOT NULL DEFAULT '',
+    `update_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `update_by` VARCHAR(64) NOT NULL DEFAULT '',
+    `version` INTEGER NOT NULL DEFAULT 0,
+    `is_del` INTEGER NOT NULL DEFAULT 0,
+    `user_id` INTEGER NOT NULL,
+    `order_id` INTEGER NOT NULL,
+    `goods_id` INTEGER NOT NULL,
+    `title` VARCHAR(255) NOT NULL DEFAULT '',
+    `price` INTEGER NOT NULL DEFAULT 0,
+    `brief` VARCHAR(255) NOT NULL DEFAULT '',
+    `num` INTEGER NOT NULL,
+    `pay_amount` INTEGER NOT NULL DEFAULT 0,
+    `subtotal` INTEGER NOT NULL DEFAULT 0,
+    `promotion_discount` INTEGER NOT NULL DEFAULT 0,
+    `coupon_code` VARCHAR(64) NOT NULL DEFAULT '',
+    `coupon_discount` INTEGER NOT NULL DEFAULT 0,
+    `shipping_fee` INTEGER NOT NULL DEFAULT 0,
+    `fence_token` INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS `idx_xx_order_detail_order_id` ON `xx_order_detail` (order_id);
+CREATE INDEX IF NOT EXISTS `idx_xx_order_detail_user_id` ON `xx_order_detail` (user_id);
+CREATE INDEX IF NOT EXISTS `idx_xx_order_detail_is_del` ON `xx_order_detail` (is_del);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS `idx_status_create_at`;
+ALTER TABLE `xx_order_detail` DROP COLUMN `status`;
+ALTER TABLE `xx_order` DROP COLUMN `status`;
@@ -0,0 +1,4 @@
+-- 订单状态：pending/unpaid 待支付 paid 已支付 shipped 已发货 completed 已完成 cancelled 已取消 timeout 支付超时
+ALTER TABLE `xx_order` ADD COLUMN `status` VARCHAR(16) NOT NULL DEFAULT 'pending';
+ALTER TABLE `xx_order_detail` ADD COLUMN `status` VARCHAR(16) NOT NULL DEFAULT 'pending';
+CREATE INDEX IF NOT EXISTS `idx_status_create_at` ON `xx_order_detail` (status, create_at);
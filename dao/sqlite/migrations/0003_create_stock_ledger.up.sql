@@ -0,0 +1,13 @@
+-- 库存补偿台账
+CREATE TABLE IF NOT EXISTS `xx_stock_ledger`(
+    `id` INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    `create_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `update_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `order_id` INTEGER NOT NULL,
+    `goods_id` INTEGER NOT NULL,
+    `num` INTEGER NOT NULL,
+    `status` VARCHAR(16) NOT NULL,
+    `source` VARCHAR(32) NOT NULL DEFAULT ''
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `uk_order_goods` ON `xx_stock_ledger` (order_id, goods_id);
+CREATE INDEX IF NOT EXISTS `idx_xx_stock_ledger_status` ON `xx_stock_ledger` (status, update_at);
@@ -0,0 +1,23 @@
+-- 发放给用户的优惠券
+CREATE TABLE IF NOT EXISTS `xx_coupon`(
+    `id` INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    `create_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `create_by` VARCHAR(64) NOT NULL DEFAULT '',
+    `update_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `update_by` VARCHAR(64) NOT NULL DEFAULT '',
+    `version` INTEGER NOT NULL DEFAULT 0,
+    `is_del` INTEGER NOT NULL DEFAULT 0,
+    `code` VARCHAR(64) NOT NULL,
+    `user_id` INTEGER NOT NULL,
+    `type` VARCHAR(16) NOT NULL,
+    `value` INTEGER NOT NULL,
+    `min_amount` INTEGER NOT NULL DEFAULT 0,
+    `max_discount` INTEGER NOT NULL DEFAULT 0,
+    `status` VARCHAR(16) NOT NULL DEFAULT 'unused',
+    `order_id` INTEGER NOT NULL DEFAULT 0,
+    `expire_at` DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `uk_code` ON `xx_coupon` (code);
+CREATE INDEX IF NOT EXISTS `idx_xx_coupon_user_id` ON `xx_coupon` (user_id);
+CREATE INDEX IF NOT EXISTS `idx_xx_coupon_order_id` ON `xx_coupon` (order_id);
+CREATE INDEX IF NOT EXISTS `idx_xx_coupon_is_del` ON `xx_coupon` (is_del);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `xx_order_detail_archive`;
+DROP TABLE IF EXISTS `xx_order_archive`;
@@ -0,0 +1,49 @@
+-- 订单归档表，保存已完成、已取消、支付超时并且超过 archive.min_age 的订单
+-- 列和订单表、订单商品表相同（id 保留原表的主键），修改订单表结构时要同步修改归档表
+CREATE TABLE IF NOT EXISTS `xx_order_archive`(
+    `id` INTEGER NOT NULL PRIMARY KEY,
+    `create_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `create_by` VARCHAR(64) NOT NULL DEFAULT '',
+    `update_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `update_by` VARCHAR(64) NOT NULL DEFAULT '',
+    `version` INTEGER NOT NULL DEFAULT 0,
+    `is_del` INTEGER NOT NULL DEFAULT 0,
+    `user_id` INTEGER NOT NULL,
+    `order_id` INTEGER NOT NULL,
+    `pay_amount` INTEGER NOT NULL DEFAULT 0,
+    `receive_address` VARCHAR(128) NOT NULL DEFAULT '',
+    `receive_name` VARCHAR(128) NOT NULL DEFAULT '',
+    `receive_phone` VARCHAR(11) NOT NULL DEFAULT '',
+    `status` VARCHAR(16) NOT NULL DEFAULT 'pending',
+    `archived_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `uk_xx_order_archive_order_id` ON `xx_order_archive` (order_id);
+CREATE INDEX IF NOT EXISTS `idx_xx_order_archive_user_id` ON `xx_order_archive` (user_id);
+
+CREATE TABLE IF NOT EXISTS `xx_order_detail_archive`(
+    `id` INTEGER NOT NULL PRIMARY KEY,
+    `create_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `create_by` VARCHAR(64) NOT NULL DEFAULT '',
+    `update_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `update_by` VARCHAR(64) NOT NULL DEFAULT '',
+    `version` INTEGER NOT NULL DEFAULT 0,
+    `is_del` INTEGER NOT NULL DEFAULT 0,
+    `user_id` INTEGER NOT NULL,
+    `order_id` INTEGER NOT NULL,
+    `goods_id` INTEGER NOT NULL,
+    `title` VARCHAR(255) NOT NULL DEFAULT '',
+    `price` INTEGER NOT NULL DEFAULT 0,
+    `brief` VARCHAR(255) NOT NULL DEFAULT '',
+    `num` INTEGER NOT NULL,
+    `pay_amount` INTEGER NOT NULL DEFAULT 0,
+    `subtotal` INTEGER NOT NULL DEFAULT 0,
+    `promotion_discount` INTEGER NOT NULL DEFAULT 0,
+    `coupon_code` VARCHAR(64) NOT NULL DEFAULT '',
+    `coupon_discount` INTEGER NOT NULL DEFAULT 0,
+    `shipping_fee` INTEGER NOT NULL DEFAULT 0,
+    `fence_token` INTEGER NOT NULL DEFAULT 0,
+    `status` VARCHAR(16) NOT NULL DEFAULT 'pending',
+    `archived_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS `idx_xx_order_detail_archive_order_id` ON `xx_order_detail_archive` (order_id);
+CREATE INDEX IF NOT EXISTS `idx_xx_order_detail_archive_user_id` ON `xx_order_detail_archive` (user_id);
@@ -0,0 +1,13 @@
+-- 订单事件表，记录订单的每一次状态变化，只追加不修改
+CREATE TABLE IF NOT EXISTS `xx_order_event`(
+    `id` INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    `create_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `order_id` INTEGER NOT NULL,
+    `user_id` INTEGER NOT NULL,
+    `from_status` VARCHAR(16) NOT NULL,
+    `to_status` VARCHAR(16) NOT NULL,
+    `actor` VARCHAR(64) NOT NULL,
+    `source` VARCHAR(32) NOT NULL,
+    `reason` VARCHAR(255) NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS `idx_xx_order_event_order_id` ON `xx_order_event` (order_id);
@@ -0,0 +1,22 @@
+package store
+
+import (
+	"context"
+
+	"order_service/dao/orm"
+
+	"google.golang.org/grpc"
+)
+
+// UnaryServerInterceptor 为每个请求记录读写状态，
+// 请求中的订单列表、订单详情查询读从库，写过数据库之后改读主库，见 orm/replica.go
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(orm.WithRequest(ctx), req)
+	}
+}
+
+// WithPrimary 标记 ctx 中的查询都读主库，用于需要读到最新数据的地方
+func WithPrimary(ctx context.Context) context.Context {
+	return orm.WithPrimary(ctx)
+}
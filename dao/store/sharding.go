@@ -0,0 +1,319 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"order_service/dao/orm"
+	"order_service/model"
+)
+
+// 订单分库分表路由
+// 订单和订单明细按用户ID分片：分片号 = 用户ID 的低 n 位（分片数量为 2^n，即分库数 × 分表数），
+// 分片号的低位是分库序号，高位是库内的分表序号（见 orm/split.go）。
+// 下单时把分片号作为基因写入订单号的低位（snowflake.GenIDWithGene），
+// 所以只有订单号时也能直接算出分片，不需要查询所有分片。
+// 库存台账、订单事件按订单号路由，和订单明细在同一个库，对账时的 JOIN 不会跨库；
+// 优惠券等不分片的表只使用第一个库。
+// 对账、运维后台的订单查询等在所有分片上并发执行后合并结果（scatter-gather），迁移、健康检查等按库执行。
+// 启用分库分表前生成的订单号不含基因，已有数据需要先按用户ID迁移到对应分片并重新生成订单号。
+
+// shardedRepository 分库分表存储
+type shardedRepository struct {
+	shards []Repository // 下标为分片号，shards[0:dbs] 是每个库的第 0 张分表
+	dbs    int          // 分库数量
+	mask   int64
+}
+
+func newShardedRepository(shards []Repository, dbs int) *shardedRepository {
+	return &shardedRepository{shards: shards, dbs: dbs, mask: int64(len(shards) - 1)}
+}
+
+// byKey 用户ID或订单号所在的分片
+func (s *shardedRepository) byKey(key int64) Repository {
+	return s.shards[key&s.mask]
+}
+
+// global 不分片的表所在的分片
+func (s *shardedRepository) global() Repository {
+	return s.shards[0]
+}
+
+// groupByShard 按分片对订单号分组
+func (s *shardedRepository) groupByShard(orderIds []int64) map[int][]int64 {
+	groups := make(map[int][]int64)
+	for _, id := range orderIds {
+		i := int(id & s.mask)
+		groups[i] = append(groups[i], id)
+	}
+	return groups
+}
+
+// databases 每个库各一个分片的下标，用于迁移、健康检查等按库执行的操作
+func (s *shardedRepository) databases() []int {
+	idx := make([]int, s.dbs)
+	for i := range idx {
+		idx[i] = i
+	}
+	return idx
+}
+
+// scatter 在 idx 指定的分片（为 nil 时为所有分片）上并发执行 fn，返回所有分片的错误
+func (s *shardedRepository) scatter(idx []int, fn func(i int, r Repository) error) error {
+	if idx == nil {
+		idx = make([]int, len(s.shards))
+		for i := range s.shards {
+			idx[i] = i
+		}
+	}
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, i := range idx {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := fn(i, s.shards[i]); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("shard %d: %w", i, err))
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (s *shardedRepository) QueryOrder(ctx context.Context, orderId int64) (model.Order, error) {
+	return s.byKey(orderId).QueryOrder(ctx, orderId)
+}
+
+func (s *shardedRepository) UpdateOrder(ctx context.Context, data model.Order) error {
+	return s.byKey(data.OrderId).UpdateOrder(ctx, data)
+}
+
+func (s *shardedRepository) CreateOrder(ctx context.Context, data *model.Order) error {
+	return s.byKey(data.UserId).CreateOrder(ctx, data)
+}
+
+func (s *shardedRepository) CreateOrderDetail(ctx context.Context, data *model.OrderDetail) error {
+	return s.byKey(data.UserId).CreateOrderDetail(ctx, data)
+}
+
+// CreateOrderWithTransation 订单号的基因和用户ID不一致时拒绝写入，否则之后按订单号查不到
+func (s *shardedRepository) CreateOrderWithTransation(ctx context.Context, order *model.Order, orderDetail *model.OrderDetail) error {
+	if order.OrderId&s.mask != order.UserId&s.mask {
+		return fmt.Errorf("order %d does not belong to the shard of user %d", order.OrderId, order.UserId)
+	}
+	return s.byKey(order.UserId).CreateOrderWithTransation(ctx, order, orderDetail)
+}
+
+func (s *shardedRepository) UpdateOrderStatus(ctx context.Context, order *model.OrderDetail, change model.StatusChange) error {
+	return s.byKey(order.OrderId).UpdateOrderStatus(ctx, order, change)
+}
+
+func (s *shardedRepository) QueryOrderList(ctx context.Context, userId int64, offset, limit int) ([]model.Order, int64, error) {
+	return s.byKey(userId).QueryOrderList(ctx, userId, offset, limit)
+}
+
+func (s *shardedRepository) QueryOrderDetail(ctx context.Context, orderId int64) (model.OrderDetail, error) {
+	return s.byKey(orderId).QueryOrderDetail(ctx, orderId)
+}
+
+func (s *shardedRepository) QueryOrderDetails(ctx context.Context, orderIds []int64) ([]model.OrderDetail, error) {
+	groups := s.groupByShard(orderIds)
+	var (
+		mu      sync.Mutex
+		details = make([]model.OrderDetail, 0, len(orderIds))
+	)
+	err := s.scatter(keys(groups), func(i int, r Repository) error {
+		rows, err := r.QueryOrderDetails(ctx, groups[i])
+		mu.Lock()
+		details = append(details, rows...)
+		mu.Unlock()
+		return err
+	})
+	return details, err
+}
+
+func (s *shardedRepository) QueryExistingOrderIds(ctx context.Context, orderIds []int64) ([]int64, error) {
+	groups := s.groupByShard(orderIds)
+	var (
+		mu  sync.Mutex
+		ids = make([]int64, 0, len(orderIds))
+	)
+	err := s.scatter(keys(groups), func(i int, r Repository) error {
+		rows, err := r.QueryExistingOrderIds(ctx, groups[i])
+		mu.Lock()
+		ids = append(ids, rows...)
+		mu.Unlock()
+		return err
+	})
+	return ids, err
+}
+
+func (s *shardedRepository) DeleteOrder(ctx context.Context, orderId int64) (model.Order, error) {
+	return s.byKey(orderId).DeleteOrder(ctx, orderId)
+}
+
+func (s *shardedRepository) RestoreOrder(ctx context.Context, orderId int64) (model.Order, error) {
+	return s.byKey(orderId).RestoreOrder(ctx, orderId)
+}
+
+func (s *shardedRepository) QueryOrderEvents(ctx context.Context, orderId int64) ([]model.OrderEvent, error) {
+	return s.byKey(orderId).QueryOrderEvents(ctx, orderId)
+}
+
+// SearchOrders 指定用户时只查询用户所在的分片；否则每个分片查询前 offset+limit 条，合并排序后取当前页
+// 总数为所有分片的总数之和，offset 越大每个分片返回的数据越多，调用方需要限制查询的深度
+func (s *shardedRepository) SearchOrders(ctx context.Context, filter model.OrderFilter, offset, limit int) ([]model.Order, int64, error) {
+	if filter.UserId > 0 {
+		return s.byKey(filter.UserId).SearchOrders(ctx, filter, offset, limit)
+	}
+	var (
+		mu     sync.Mutex
+		total  int64
+		merged []model.Order
+	)
+	err := s.scatter(nil, func(_ int, r Repository) error {
+		rows, n, err := r.SearchOrders(ctx, filter, 0, offset+limit)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		merged = append(merged, rows...)
+		total += n
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Slice(merged, func(i, j int) bool { return model.OrderNewerThan(merged[i], merged[j]) })
+	if offset >= len(merged) {
+		return []model.Order{}, total, nil
+	}
+	return merged[offset:min(offset+limit, len(merged))], total, nil
+}
+
+func (s *shardedRepository) RecordStockDeduction(ctx context.Context, orderId, goodsId, num int64) error {
+	return s.byKey(orderId).RecordStockDeduction(ctx, orderId, goodsId, num)
+}
+
+func (s *shardedRepository) ClaimStockRollback(ctx context.Context, orderId, goodsId, num int64, source string) (bool, error) {
+	return s.byKey(orderId).ClaimStockRollback(ctx, orderId, goodsId, num, source)
+}
+
+func (s *shardedRepository) FinishStockRollback(ctx context.Context, orderId, goodsId int64, ok bool) error {
+	return s.byKey(orderId).FinishStockRollback(ctx, orderId, goodsId, ok)
+}
+
+func (s *shardedRepository) QueryLedgerDrifts(ctx context.Context, since time.Time, grace time.Duration) ([]model.LedgerDrift, error) {
+	var (
+		mu     sync.Mutex
+		drifts []model.LedgerDrift
+	)
+	err := s.scatter(nil, func(_ int, r Repository) error {
+		rows, err := r.QueryLedgerDrifts(ctx, since, grace)
+		mu.Lock()
+		drifts = append(drifts, rows...)
+		mu.Unlock()
+		return err
+	})
+	return drifts, err
+}
+
+func (s *shardedRepository) QueryCoupon(ctx context.Context, code string) (model.Coupon, error) {
+	return s.global().QueryCoupon(ctx, code)
+}
+
+func (s *shardedRepository) ReserveCoupon(ctx context.Context, code string, userId, orderId int64) (bool, error) {
+	return s.global().ReserveCoupon(ctx, code, userId, orderId)
+}
+
+func (s *shardedRepository) ConfirmCoupon(ctx context.Context, orderId int64) error {
+	return s.global().ConfirmCoupon(ctx, orderId)
+}
+
+func (s *shardedRepository) ReleaseCoupon(ctx context.Context, orderId int64) (string, error) {
+	return s.global().ReleaseCoupon(ctx, orderId)
+}
+
+// MigrateUp 所有库逐个执行迁移（同时创建和修改库中的分表），某个库失败时停止，已经迁移的库不回退
+func (s *shardedRepository) MigrateUp(ctx context.Context, steps int) ([]orm.Migration, error) {
+	var done []orm.Migration
+	for i, r := range s.shards[:s.dbs] {
+		list, err := r.MigrateUp(ctx, steps)
+		done = append(done, list...)
+		if err != nil {
+			return done, fmt.Errorf("shard %d: %w", i, err)
+		}
+	}
+	return done, nil
+}
+
+// MigrateDown 所有库逐个回退迁移，某个库失败时停止
+func (s *shardedRepository) MigrateDown(ctx context.Context, steps int) ([]orm.Migration, error) {
+	var done []orm.Migration
+	for i, r := range s.shards[:s.dbs] {
+		list, err := r.MigrateDown(ctx, steps)
+		done = append(done, list...)
+		if err != nil {
+			return done, fmt.Errorf("shard %d: %w", i, err)
+		}
+	}
+	return done, nil
+}
+
+func (s *shardedRepository) ForceMigration(ctx context.Context, version int64) error {
+	return s.scatter(s.databases(), func(_ int, r Repository) error {
+		return r.ForceMigration(ctx, version)
+	})
+}
+
+// MigrationStatus 返回所有库的迁移状态，按分库顺序排列
+func (s *shardedRepository) MigrationStatus(ctx context.Context) ([]orm.MigrationState, error) {
+	states := make([][]orm.MigrationState, s.dbs)
+	err := s.scatter(s.databases(), func(i int, r Repository) (err error) {
+		states[i], err = r.MigrationStatus(ctx)
+		return err
+	})
+	var all []orm.MigrationState
+	for _, list := range states {
+		all = append(all, list...)
+	}
+	return all, err
+}
+
+func (s *shardedRepository) CheckSchema(ctx context.Context) error {
+	return s.scatter(s.databases(), func(_ int, r Repository) error {
+		return r.CheckSchema(ctx)
+	})
+}
+
+func (s *shardedRepository) Ping(ctx context.Context) error {
+	return s.scatter(s.databases(), func(_ int, r Repository) error {
+		return r.Ping(ctx)
+	})
+}
+
+func (s *shardedRepository) Close() error {
+	return s.scatter(s.databases(), func(_ int, r Repository) error {
+		return r.Close()
+	})
+}
+
+func keys(groups map[int][]int64) []int {
+	idx := make([]int, 0, len(groups))
+	for i := range groups {
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+	return idx
+}
@@ -0,0 +1,183 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"order_service/config"
+	"order_service/model"
+	"order_service/third_party/snowflake"
+)
+
+// openSharded 在 dir 下使用两个 sqlite 库，每个库分成 tables 张表，并执行迁移
+func openSharded(t *testing.T, dir string, tables int) *config.ShardingConfig {
+	t.Helper()
+	sc := &config.ShardingConfig{
+		Tables: tables,
+		Shards: []config.ShardConfig{
+			{DB: filepath.Join(dir, "s0.db")},
+			{DB: filepath.Join(dir, "s1.db")},
+		},
+	}
+	err := Init(&config.SrvConfig{StorageConfig: &config.StorageConfig{Driver: DriverSQLite}, ShardingConfig: sc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Close() })
+	if _, err := Repo().MigrateUp(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := snowflake.Init("2020-12-31", 1, sc.GeneBits()); err != nil {
+		t.Fatal(err)
+	}
+	return sc
+}
+
+// createOrders 为 users 中的每个用户创建一个订单，返回订单号
+func createOrders(t *testing.T, users ...int64) []int64 {
+	t.Helper()
+	ids := make([]int64, len(users))
+	for i, u := range users {
+		ids[i] = snowflake.GenIDWithGene(u)
+		err := Repo().CreateOrderWithTransation(context.Background(),
+			&model.Order{OrderId: ids[i], UserId: u, Status: model.OrderStatusPending},
+			&model.OrderDetail{OrderId: ids[i], UserId: u, GoodsId: 1, Num: 1, Status: model.OrderStatusPending})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ids
+}
+
+func TestOrdersStayInUserShard(t *testing.T) {
+	ctx := context.Background()
+	openSharded(t, t.TempDir(), 2)
+	ids := createOrders(t, 1, 2, 3, 4, 5, 6, 7, 8)
+
+	// 分片号的低位是分库序号，高位是分表序号
+	want := map[string][]int64{
+		"s0/xx_order_0": {4, 8},
+		"s1/xx_order_0": {1, 5},
+		"s0/xx_order_1": {2, 6},
+		"s1/xx_order_1": {3, 7},
+	}
+	list := Shards()
+	if len(list) != len(want) {
+		t.Fatalf("got %d shards, want %d", len(list), len(want))
+	}
+	for _, s := range list {
+		name := s.Name() + "/" + s.Table()
+		orders, _, err := s.(Repository).SearchOrders(ctx, model.OrderFilter{}, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int64
+		for _, o := range orders {
+			got = append(got, o.UserId)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, want[name]) {
+			t.Errorf("%s has orders of users %v, want %v", name, got, want[name])
+		}
+	}
+
+	// 只有订单号时也能找到订单
+	for i, id := range ids {
+		o, err := Repo().QueryOrder(ctx, id)
+		if err != nil || o.UserId != int64(i+1) {
+			t.Errorf("QueryOrder(%d) = user %d, %v; want user %d", id, o.UserId, err, i+1)
+		}
+	}
+}
+
+func TestSearchOrdersAcrossShards(t *testing.T) {
+	ctx := context.Background()
+	openSharded(t, t.TempDir(), 2)
+	var ids []int64
+	for u := int64(1); u <= 7; u++ {
+		ids = append(ids, createOrders(t, u)...)
+		time.Sleep(time.Millisecond) // 创建时间不同，合并后按创建时间倒序
+	}
+	paid := &model.OrderDetail{OrderId: ids[2], Status: model.OrderStatusPaid}
+	if err := Repo().UpdateOrderStatus(ctx, paid, model.StatusChange{Source: model.EventSourceRPC}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 逐页翻完，每页都是所有分片合并后的结果
+	var users []int64
+	for offset := 0; ; offset += 3 {
+		page, total, err := Repo().SearchOrders(ctx, model.OrderFilter{}, offset, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 7 {
+			t.Errorf("offset %d: total = %d, want 7", offset, total)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, o := range page {
+			users = append(users, o.UserId)
+		}
+	}
+	if want := []int64{7, 6, 5, 4, 3, 2, 1}; !slices.Equal(users, want) {
+		t.Errorf("pages = %v, want %v", users, want)
+	}
+
+	page, total, err := Repo().SearchOrders(ctx, model.OrderFilter{UserId: 6}, 0, 10)
+	if err != nil || total != 1 || len(page) != 1 || page[0].OrderId != ids[5] {
+		t.Errorf("search user 6 = %v, %d, %v; want order %d", page, total, err, ids[5])
+	}
+	page, total, err = Repo().SearchOrders(ctx, model.OrderFilter{Statuses: []string{model.OrderStatusPaid}}, 0, 10)
+	if err != nil || total != 1 || len(page) != 1 || page[0].OrderId != ids[2] {
+		t.Errorf("search paid = %v, %d, %v; want order %d", page, total, err, ids[2])
+	}
+}
+
+func TestSplitExistingDatabases(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	openSharded(t, dir, 0)
+	states, err := Repo().MigrationStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Close()
+
+	// 已经迁移过的库开启分表，按模板表创建分表，迁移仍然按库执行
+	openSharded(t, dir, 4)
+	if got := len(Databases()); got != 2 {
+		t.Errorf("got %d databases, want 2", got)
+	}
+	if got := len(Shards()); got != 8 {
+		t.Errorf("got %d shards, want 8", got)
+	}
+	if err := Repo().CheckSchema(ctx); err != nil {
+		t.Errorf("CheckSchema after splitting: %v", err)
+	}
+	split, err := Repo().MigrationStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(split) != len(states) {
+		t.Errorf("got %d migration states, want %d", len(split), len(states))
+	}
+
+	// 回退再执行最后一个迁移，分表和模板表的结构保持一致
+	if _, err := Repo().MigrateDown(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Repo().MigrateUp(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := Repo().CheckSchema(ctx); err != nil {
+		t.Errorf("CheckSchema after re-running migration: %v", err)
+	}
+	ids := createOrders(t, 13)
+	if _, err := Repo().QueryOrder(ctx, ids[0]); err != nil {
+		t.Errorf("QueryOrder(%d) = %v", ids[0], err)
+	}
+}
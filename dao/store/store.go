@@ -0,0 +1,257 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"order_service/config"
+	"order_service/dao/mysql"
+	"order_service/dao/orm"
+	"order_service/dao/sqlite"
+	"order_service/model"
+
+	"go.uber.org/zap"
+)
+
+// 订单服务的存储接口
+// biz 层和缓存层通过 Repo() 访问数据库，不直接依赖具体的数据库，
+// 使用哪种数据库由配置 storage.driver 决定：线上使用 MySQL，本地开发和 CI 可以使用 SQLite。
+
+// 存储后端
+const (
+	DriverMySQL  = "mysql"
+	DriverSQLite = "sqlite"
+)
+
+// OrderRepository 订单和订单明细
+type OrderRepository interface {
+	QueryOrder(ctx context.Context, orderId int64) (model.Order, error)
+	UpdateOrder(ctx context.Context, data model.Order) error
+	CreateOrder(ctx context.Context, data *model.Order) error
+	CreateOrderDetail(ctx context.Context, data *model.OrderDetail) error
+	CreateOrderWithTransation(ctx context.Context, order *model.Order, orderDetail *model.OrderDetail) error
+	UpdateOrderStatus(ctx context.Context, order *model.OrderDetail, change model.StatusChange) error
+	QueryOrderList(ctx context.Context, userId int64, offset, limit int) ([]model.Order, int64, error)
+	QueryOrderDetail(ctx context.Context, orderId int64) (model.OrderDetail, error)
+	QueryOrderDetails(ctx context.Context, orderIds []int64) ([]model.OrderDetail, error)
+	QueryExistingOrderIds(ctx context.Context, orderIds []int64) ([]int64, error)
+	DeleteOrder(ctx context.Context, orderId int64) (model.Order, error)
+	RestoreOrder(ctx context.Context, orderId int64) (model.Order, error)
+	QueryOrderEvents(ctx context.Context, orderId int64) ([]model.OrderEvent, error)
+	SearchOrders(ctx context.Context, filter model.OrderFilter, offset, limit int) ([]model.Order, int64, error)
+}
+
+// TimeoutScanner 超时订单扫描，在一个库内按自增ID范围分批查询
+type TimeoutScanner interface {
+	GetMinOrderIdAfterTime(ctx context.Context, timestamp time.Time) (int64, error)
+	GetShardParams(ctx context.Context, minOrderId int64) ([]model.ShardParam, error)
+	QueryTimeoutOrdersByShard(ctx context.Context, startID, endID int64, timeoutTime time.Time) ([]model.OrderDetail, error)
+}
+
+// Archiver 冷订单归档，在一个库内分批把订单移动到归档表，见 orm/archive.go
+type Archiver interface {
+	ArchiveOrders(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+// StockLedgerRepository 库存补偿台账
+type StockLedgerRepository interface {
+	RecordStockDeduction(ctx context.Context, orderId, goodsId, num int64) error
+	ClaimStockRollback(ctx context.Context, orderId, goodsId, num int64, source string) (bool, error)
+	FinishStockRollback(ctx context.Context, orderId, goodsId int64, ok bool) error
+	QueryLedgerDrifts(ctx context.Context, since time.Time, grace time.Duration) ([]model.LedgerDrift, error)
+}
+
+// CouponRepository 发放给用户的优惠券
+type CouponRepository interface {
+	QueryCoupon(ctx context.Context, code string) (model.Coupon, error)
+	ReserveCoupon(ctx context.Context, code string, userId, orderId int64) (bool, error)
+	ConfirmCoupon(ctx context.Context, orderId int64) error
+	ReleaseCoupon(ctx context.Context, orderId int64) (string, error)
+}
+
+// SchemaMigrator 表结构迁移
+type SchemaMigrator interface {
+	MigrateUp(ctx context.Context, steps int) ([]orm.Migration, error)
+	MigrateDown(ctx context.Context, steps int) ([]orm.Migration, error)
+	ForceMigration(ctx context.Context, version int64) error
+	MigrationStatus(ctx context.Context) ([]orm.MigrationState, error)
+	CheckSchema(ctx context.Context) error
+}
+
+// Repository 订单服务使用的全部存储操作
+// 分库分表时按用户ID（订单号中的基因）路由到对应的分片，见 sharding.go
+type Repository interface {
+	OrderRepository
+	StockLedgerRepository
+	CouponRepository
+	SchemaMigrator
+
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// Shard 一个分片（一个库中的一张订单分表），不分库也不分表时只有一个
+// 超时扫描、归档等按表进行的操作通过 Shards() 逐个分片执行，连接池指标等按库进行的操作通过 Databases() 逐个库执行
+type Shard interface {
+	Repository
+	TimeoutScanner
+	Archiver
+
+	Name() string  // 数据库名称
+	Table() string // 订单表的表名，分表时为分表的表名
+	SQLDB() (*sql.DB, error)
+}
+
+var (
+	repo   Repository
+	dbs    []*orm.Repository // 所有库，分表时为每个库的第 0 张分表
+	shards []*orm.Repository // 所有分片，下标为分片号：低位是分库序号，高位是分表序号
+	driver string
+)
+
+// Init 按配置连接数据库，配置了 sharding.shards 时连接所有分片库，配置了 sharding.tables 时每个库再分表
+func Init(cfg *config.SrvConfig) (err error) {
+	driver = cfg.StorageConfig.DriverName()
+	if driver != DriverMySQL && driver != DriverSQLite {
+		return fmt.Errorf("unknown storage driver: %s", driver)
+	}
+	sc := cfg.ShardingConfig
+	dbs = nil
+	if sc == nil || len(sc.Shards) == 0 {
+		r, err := open(cfg, nil)
+		if err != nil {
+			return err
+		}
+		dbs = []*orm.Repository{r}
+	} else {
+		for i := range sc.Shards {
+			r, err := open(cfg, &sc.Shards[i])
+			if err != nil {
+				Close()
+				return fmt.Errorf("open shard %d failed: %w", i, err)
+			}
+			dbs = append(dbs, r)
+		}
+	}
+
+	tables := 1
+	if sc != nil && sc.Tables > 1 {
+		tables = sc.Tables
+	}
+	shards = make([]*orm.Repository, len(dbs)*tables)
+	for i, db := range dbs {
+		for t, r := range db.SplitTables(tables, sc.DBBits()) {
+			shards[t*len(dbs)+i] = r
+		}
+	}
+	if len(shards) == 1 {
+		repo = shards[0]
+		return nil
+	}
+	list := make([]Repository, len(shards))
+	for i, r := range shards {
+		list[i] = r
+	}
+	repo = newShardedRepository(list, len(dbs))
+	return nil
+}
+
+// open 连接一个库，shard 为 nil 时使用 mysql（或 storage.sqlite_path）配置的库
+func open(cfg *config.SrvConfig, shard *config.ShardConfig) (*orm.Repository, error) {
+	if driver == DriverSQLite {
+		path := cfg.StorageConfig.SQLitePath
+		if shard != nil {
+			path = shard.DB
+		}
+		return sqlite.Open(path)
+	}
+	return mysql.Open(shardMySQLConfig(cfg.MySQLConfig, shard))
+}
+
+// shardMySQLConfig 分片库的 MySQL 配置，账号和连接池大小使用 mysql 的配置
+func shardMySQLConfig(mysqlCfg *config.MySQLConfig, shard *config.ShardConfig) *config.MySQLConfig {
+	if shard == nil {
+		return mysqlCfg
+	}
+	c := *mysqlCfg
+	c.DB = shard.DB
+	if len(shard.Host) > 0 {
+		c.Host = shard.Host
+	}
+	if shard.Port > 0 {
+		c.Port = shard.Port
+	}
+	c.Replicas = shard.Replicas
+	return &c
+}
+
+// Repo 返回当前使用的存储
+func Repo() Repository {
+	return repo
+}
+
+// Shards 返回所有分片，分表时同一个库的每张分表各是一个分片
+func Shards() []Shard {
+	list := make([]Shard, len(shards))
+	for i, r := range shards {
+		list[i] = r
+	}
+	return list
+}
+
+// Databases 返回所有库，不分库时只有一个
+func Databases() []Shard {
+	list := make([]Shard, len(dbs))
+	for i, r := range dbs {
+		list[i] = r
+	}
+	return list
+}
+
+// Reload 配置热加载时调整连接池大小和允许的最大复制延迟，只有 MySQL 支持
+func Reload(old, cur *config.SrvConfig) error {
+	if repo == nil {
+		return errors.New("storage not initialized")
+	}
+	if !reflect.DeepEqual(old.ShardingConfig, cur.ShardingConfig) {
+		zap.L().Warn("sharding config changed, restart required to take effect")
+	}
+	if driver != DriverMySQL {
+		return nil
+	}
+	for i, r := range dbs {
+		var oldShard, curShard *config.ShardConfig
+		if old.ShardingConfig != nil && i < len(old.ShardingConfig.Shards) {
+			oldShard = &old.ShardingConfig.Shards[i]
+		}
+		if cur.ShardingConfig != nil && i < len(cur.ShardingConfig.Shards) {
+			curShard = &cur.ShardingConfig.Shards[i]
+		}
+		err := mysql.Reload(r, shardMySQLConfig(old.MySQLConfig, oldShard), shardMySQLConfig(cur.MySQLConfig, curShard))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ping 检查数据库连接是否可用（供健康检查使用）
+func Ping(ctx context.Context) error {
+	if repo == nil {
+		return errors.New("storage not initialized")
+	}
+	return repo.Ping(ctx)
+}
+
+// Close 关闭数据库连接
+func Close() error {
+	var errs []error
+	for _, r := range dbs {
+		errs = append(errs, r.Close())
+	}
+	return errors.Join(errs...)
+}
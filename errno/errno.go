@@ -10,4 +10,28 @@ var (
 	ErrUpdateFailed = errors.New("update data failed")
 
 	ErrOrderNotFound = errors.New("not found order")
+
+	ErrInvalidStatus = errors.New("invalid order status")
+
+	ErrStatusNotAllowed = errors.New("order status can only be set by admin")
+
+	ErrOrderNotDeletable = errors.New("order can not be deleted before it is closed")
+
+	ErrOrderLocked = errors.New("order is locked by others")
+
+	ErrOrderLockLost = errors.New("order lock lost")
+
+	ErrJobRunning = errors.New("job is running on another instance")
+
+	ErrJobLockLost = errors.New("job lock lost")
+
+	ErrStaleFenceToken = errors.New("stale fencing token")
+
+	ErrInvalidPrice = errors.New("invalid goods price")
+
+	ErrInvalidCoupon = errors.New("invalid coupon")
+
+	ErrCouponNotApplicable = errors.New("coupon not applicable")
+
+	ErrPageTooDeep = errors.New("page too deep")
 )
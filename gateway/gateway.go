@@ -0,0 +1,135 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"order_service/auth"
+	"order_service/config"
+	"order_service/proto"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// HTTP/JSON 网关
+// 将 order.proto、admin.proto 中带 google.api.http 注解的接口以 REST 方式对外暴露，
+// 请求经由本机的 gRPC 端口转发到 OrderSrv，gRPC 服务开启 TLS 时网关也通过 TLS 连接，
+// 同时网关本身使用 HTTPS，开启 mTLS 时 HTTP 请求也要提供客户端证书。
+// Admin 接口（修改日志级别、删除订单等）只有开启鉴权（auth.enable）时才通过网关暴露，
+// 没有开启鉴权时只能直接调用 gRPC 接口。
+
+var srv *http.Server
+
+// Init 启动 HTTP 网关
+// grpcAddr 为本服务 gRPC 监听地址，creds 为连接 gRPC 服务使用的传输凭证，cfg.Port 为 0 时不启动网关
+// tlsConfig 不为 nil 时网关使用 HTTPS
+func Init(cfg *config.GatewayConfig, grpcAddr string, creds credentials.TransportCredentials, tlsConfig *tls.Config) error {
+	if cfg == nil || cfg.Port == 0 {
+		return nil
+	}
+
+	gwMux := runtime.NewServeMux(
+		runtime.WithErrorHandler(errorHandler),
+	)
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}
+	err := proto.RegisterOrderHandlerFromEndpoint(context.Background(), gwMux, grpcAddr, opts)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/v1/", gwMux)
+	if auth.Enabled() {
+		err = proto.RegisterAdminHandlerFromEndpoint(context.Background(), gwMux, grpcAddr, opts)
+		if err != nil {
+			return err
+		}
+		mux.Handle("/admin/", requireAuth(gwMux))
+	} else {
+		zap.L().Warn("auth is disabled, admin api is not exposed on http gateway")
+	}
+	mux.HandleFunc("/swagger/order.swagger.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(proto.OrderOpenAPI)
+	})
+
+	srv = &http.Server{
+		Addr:      fmt.Sprintf(":%d", cfg.Port),
+		Handler:   mux,
+		TLSConfig: tlsConfig,
+	}
+	go func() {
+		var err error
+		if tlsConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			zap.L().Error("gateway ListenAndServe failed", zap.Error(err))
+		}
+	}()
+	zap.L().Info("http gateway start", zap.Int("port", cfg.Port))
+	return nil
+}
+
+// Exit 关闭 HTTP 网关，等待处理中的请求完成
+func Exit() error {
+	if srv == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+// requireAuth 鉴权在运行期间通过配置热加载关闭后，网关上的 Admin 接口（删除、恢复订单等）不再可用
+func requireAuth(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !auth.Enabled() {
+			http.NotFound(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// errorBody 网关统一的错误返回格式
+type errorBody struct {
+	Code    int32  `json:"code"`    // gRPC 错误码
+	Message string `json:"message"` // 错误信息
+}
+
+// errorHandler 将 gRPC 错误码映射为 HTTP 状态码，并以统一的 JSON 格式返回
+// 错误详情中有 RetryInfo 时（例如被限流）设置 Retry-After
+func errorHandler(ctx context.Context, mux *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	st := status.Convert(err)
+	w.Header().Set("Content-Type", "application/json")
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok && ri.GetRetryDelay() != nil {
+			seconds := int64(math.Ceil(ri.GetRetryDelay().AsDuration().Seconds()))
+			w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+		}
+	}
+	w.WriteHeader(runtime.HTTPStatusFromCode(st.Code()))
+	json.NewEncoder(w).Encode(errorBody{
+		Code:    int32(st.Code()),
+		Message: st.Message(),
+	})
+}
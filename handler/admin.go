@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"order_service/biz/order"
+	"order_service/config"
+	"order_service/errno"
+	"order_service/healthcheck"
+	"order_service/logger"
+	"order_service/proto"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AdminSrv 运维管理接口
+type AdminSrv struct {
+	proto.UnimplementedAdminServer
+}
+
+// DependencyReport 返回各依赖最近一次的健康检查结果
+func (s *AdminSrv) DependencyReport(ctx context.Context, req *proto.DependencyReportReq) (*proto.DependencyReportResp, error) {
+	report := healthcheck.Report()
+	resp := &proto.DependencyReportResp{
+		Serving:      healthcheck.Serving(),
+		Dependencies: make([]*proto.DependencyStatus, 0, len(report)),
+	}
+	for _, st := range report {
+		resp.Dependencies = append(resp.Dependencies, &proto.DependencyStatus{
+			Name:      st.Name,
+			Healthy:   st.Healthy,
+			Error:     st.Error,
+			LatencyMs: st.Latency.Milliseconds(),
+			CheckedAt: st.CheckedAt.Unix(),
+		})
+	}
+	return resp, nil
+}
+
+// GetLogLevel 查询当前的日志级别
+func (s *AdminSrv) GetLogLevel(ctx context.Context, req *proto.GetLogLevelReq) (*proto.LogLevelResp, error) {
+	return logLevelResp(), nil
+}
+
+// SetLogLevel 修改全局或某个包的日志级别，修改在下次配置文件热加载前有效
+func (s *AdminSrv) SetLogLevel(ctx context.Context, req *proto.SetLogLevelReq) (*proto.LogLevelResp, error) {
+	var err error
+	if len(req.GetPackage()) == 0 {
+		err = logger.SetLevel(req.GetLevel())
+	} else {
+		err = logger.SetPackageLevel(req.GetPackage(), req.GetLevel())
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	logger.Ctx(ctx).Info("log level changed",
+		zap.String("package", req.GetPackage()),
+		zap.String("level", req.GetLevel()))
+	return logLevelResp(), nil
+}
+
+func logLevelResp() *proto.LogLevelResp {
+	global, packages := logger.Levels()
+	return &proto.LogLevelResp{Level: global, Packages: packages}
+}
+
+// GetArchiveStatus 查询冷订单归档任务的状态
+func (s *AdminSrv) GetArchiveStatus(ctx context.Context, req *proto.GetArchiveStatusReq) (*proto.ArchiveStatusResp, error) {
+	return archiveStatusResp(ctx)
+}
+
+// SetArchivePaused 暂停或恢复冷订单归档任务
+func (s *AdminSrv) SetArchivePaused(ctx context.Context, req *proto.SetArchivePausedReq) (*proto.ArchiveStatusResp, error) {
+	if err := order.SetArchivePaused(ctx, req.GetPaused()); err != nil {
+		logger.Ctx(ctx).Error("set archive paused failed", zap.Error(err))
+		return nil, status.Error(codes.Internal, "内部错误")
+	}
+	return archiveStatusResp(ctx)
+}
+
+func archiveStatusResp(ctx context.Context) (*proto.ArchiveStatusResp, error) {
+	st, err := order.ArchiveState(ctx)
+	if err != nil {
+		logger.Ctx(ctx).Error("get archive state failed", zap.Error(err))
+		return nil, status.Error(codes.Internal, "内部错误")
+	}
+	cfg := config.Get().ArchiveConfig
+	resp := &proto.ArchiveStatusResp{
+		Enabled:      cfg != nil && cfg.Interval > 0,
+		Paused:       st.Paused,
+		LastArchived: st.LastArchived,
+		LastError:    st.LastError,
+	}
+	if !st.LastRunAt.IsZero() {
+		resp.LastRunAt = st.LastRunAt.Unix()
+	}
+	return resp, nil
+}
+
+// DeleteOrder 软删除已经结束的订单
+func (s *AdminSrv) DeleteOrder(ctx context.Context, req *proto.DeleteOrderReq) (*proto.OrderDeletionResp, error) {
+	if req.GetOrderId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "请求参数有误")
+	}
+	err := order.Delete(ctx, req.GetOrderId())
+	switch {
+	case errors.Is(err, errno.ErrOrderNotFound):
+		return nil, status.Error(codes.NotFound, "订单不存在或已删除")
+	case errors.Is(err, errno.ErrOrderNotDeletable):
+		return nil, status.Error(codes.FailedPrecondition, "订单未结束，不能删除")
+	case err != nil:
+		logger.Ctx(ctx).Error("order.Delete failed", zap.Error(err))
+		return nil, status.Error(codes.Internal, "内部错误")
+	}
+	return &proto.OrderDeletionResp{OrderId: req.GetOrderId(), Deleted: true}, nil
+}
+
+// RestoreOrder 恢复已经软删除的订单
+func (s *AdminSrv) RestoreOrder(ctx context.Context, req *proto.RestoreOrderReq) (*proto.OrderDeletionResp, error) {
+	if req.GetOrderId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "请求参数有误")
+	}
+	err := order.Restore(ctx, req.GetOrderId())
+	switch {
+	case errors.Is(err, errno.ErrOrderNotFound):
+		return nil, status.Error(codes.NotFound, "订单不存在或未删除")
+	case err != nil:
+		logger.Ctx(ctx).Error("order.Restore failed", zap.Error(err))
+		return nil, status.Error(codes.Internal, "内部错误")
+	}
+	return &proto.OrderDeletionResp{OrderId: req.GetOrderId(), Deleted: false}, nil
+}
+
+// SearchOrders 按条件查询所有用户的订单
+func (s *AdminSrv) SearchOrders(ctx context.Context, req *proto.SearchOrdersReq) (*proto.OrderListResp, error) {
+	if req.GetUserId() < 0 || req.GetCreatedAfter() < 0 || req.GetCreatedBefore() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "请求参数有误")
+	}
+	resp, err := order.Search(ctx, req)
+	switch {
+	case errors.Is(err, errno.ErrInvalidStatus):
+		return nil, status.Error(codes.InvalidArgument, "订单状态有误")
+	case errors.Is(err, errno.ErrPageTooDeep):
+		return nil, status.Error(codes.InvalidArgument, "翻页过深，请缩小查询条件")
+	case err != nil:
+		logger.Ctx(ctx).Error("order.Search failed", zap.Error(err))
+		return nil, status.Error(codes.Internal, "内部错误")
+	}
+	return resp, nil
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"order_service/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSearchOrdersRejectsBadQuery(t *testing.T) {
+	tests := map[string]*proto.SearchOrdersReq{
+		"negative user":   {UserId: -1},
+		"unknown status":  {Status: 9},
+		"page too deep":   {PageNum: 11, PageSize: 100},
+		"negative after":  {CreatedAfter: -1},
+		"negative before": {CreatedBefore: -1},
+	}
+	for name, req := range tests {
+		_, err := (&AdminSrv{}).SearchOrders(context.Background(), req)
+		if got := status.Code(err); got != codes.InvalidArgument {
+			t.Errorf("%s: code = %v, want InvalidArgument", name, got)
+		}
+	}
+}
@@ -2,9 +2,13 @@ package handler
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"order_service/biz/order"
+	"order_service/errno"
+	"order_service/logger"
+	"order_service/model"
 	"order_service/proto"
+	"unicode/utf8"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
@@ -20,20 +24,116 @@ type OrderSrv struct {
 // 简化版：生成订单号 查询商品信息 扣库存
 // 1. 生成订单号 2.查询商品信息 3.扣库存
 func (s *OrderSrv) CreateOrder(ctx context.Context, req *proto.CreateOrderReq) (*proto.Response, error) {
-	fmt.Println("in CreateOrder ... ") // 打印进入方法的日志
+	logger.Ctx(ctx).Debug("in CreateOrder ... ") // 打印进入方法的日志
 
 	// 参数处理
-	if req.GetUserId() <= 0 { // 检查请求中的用户ID是否有效
+	if req.GetUserId() <= 0 || req.GetGoodsId() <= 0 || req.GetNum() <= 0 { // 检查请求中的用户ID、商品和数量是否有效
 		// 无效的请求
 		return nil, status.Error(codes.InvalidArgument, "请求参数有误") // 返回 gRPC 的 InvalidArgument 错误
 	}
 
 	// 业务处理
 	resp, err := order.Create(ctx, req) // 调用业务逻辑层的 Create 方法处理订单创建
+	if errors.Is(err, errno.ErrInvalidCoupon) || errors.Is(err, errno.ErrCouponNotApplicable) {
+		return nil, status.Error(codes.InvalidArgument, "优惠券不可用")
+	}
+	if errors.Is(err, errno.ErrInvalidPrice) {
+		return nil, status.Error(codes.FailedPrecondition, "商品价格有误")
+	}
 	if err != nil {
-		zap.L().Error("order.Create failed", zap.Error(err)) // 记录错误日志
+		logger.Ctx(ctx).Error("order.Create failed", zap.Error(err)) // 记录错误日志
 		return nil, status.Error(codes.Internal, "内部错误")     // 返回 gRPC 的 Internal 错误
 	}
 
 	return resp, nil // 返回空响应，表示操作成功
 }
+
+// OrderList 查询用户的订单列表
+func (s *OrderSrv) OrderList(ctx context.Context, req *proto.OrderListReq) (*proto.OrderListResp, error) {
+	if req.GetUserId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "请求参数有误")
+	}
+
+	resp, err := order.List(ctx, req)
+	if err != nil {
+		logger.Ctx(ctx).Error("order.List failed", zap.Error(err))
+		return nil, status.Error(codes.Internal, "内部错误")
+	}
+	return resp, nil
+}
+
+// OrderDetail 查询订单详情
+func (s *OrderSrv) OrderDetail(ctx context.Context, req *proto.OrderDetailReq) (*proto.OrderDetailInfo, error) {
+	if req.GetOrderId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "请求参数有误")
+	}
+
+	resp, err := order.Detail(ctx, req)
+	if errors.Is(err, errno.ErrOrderNotFound) {
+		return nil, status.Error(codes.NotFound, "订单不存在")
+	}
+	if err != nil {
+		logger.Ctx(ctx).Error("order.Detail failed", zap.Error(err))
+		return nil, status.Error(codes.Internal, "内部错误")
+	}
+	return resp, nil
+}
+
+// GetOrderHistory 查询订单的状态变化历史
+func (s *OrderSrv) GetOrderHistory(ctx context.Context, req *proto.OrderHistoryReq) (*proto.OrderHistoryResp, error) {
+	if req.GetOrderId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "请求参数有误")
+	}
+
+	resp, err := order.History(ctx, req)
+	if errors.Is(err, errno.ErrOrderNotFound) {
+		return nil, status.Error(codes.NotFound, "订单不存在")
+	}
+	if err != nil {
+		logger.Ctx(ctx).Error("order.History failed", zap.Error(err))
+		return nil, status.Error(codes.Internal, "内部错误")
+	}
+	return resp, nil
+}
+
+// QuoteOrder 订单试算，返回价格明细
+func (s *OrderSrv) QuoteOrder(ctx context.Context, req *proto.QuoteOrderReq) (*proto.OrderQuote, error) {
+	if req.GetUserId() <= 0 || req.GetGoodsId() <= 0 || req.GetNum() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "请求参数有误")
+	}
+
+	resp, err := order.Quote(ctx, req)
+	switch {
+	case errors.Is(err, errno.ErrInvalidCoupon), errors.Is(err, errno.ErrCouponNotApplicable):
+		return nil, status.Error(codes.InvalidArgument, "优惠券不可用")
+	case errors.Is(err, errno.ErrInvalidPrice):
+		return nil, status.Error(codes.FailedPrecondition, "商品价格有误")
+	case err != nil:
+		logger.Ctx(ctx).Error("order.Quote failed", zap.Error(err))
+		return nil, status.Error(codes.Internal, "内部错误")
+	}
+	return resp, nil
+}
+
+// UpdateOrderStatus 更新订单状态
+func (s *OrderSrv) UpdateOrderStatus(ctx context.Context, req *proto.OrderStatus) (*proto.Response, error) {
+	if req.GetOrderId() <= 0 || utf8.RuneCountInString(req.GetReason()) > model.MaxEventReasonLen {
+		return nil, status.Error(codes.InvalidArgument, "请求参数有误")
+	}
+
+	err := order.UpdateStatus(ctx, req)
+	switch {
+	case errors.Is(err, errno.ErrInvalidStatus):
+		return nil, status.Error(codes.InvalidArgument, "订单状态有误")
+	case errors.Is(err, errno.ErrStatusNotAllowed):
+		return nil, status.Error(codes.PermissionDenied, "没有权限")
+	case errors.Is(err, errno.ErrOrderNotFound):
+		return nil, status.Error(codes.NotFound, "订单不存在")
+	case errors.Is(err, errno.ErrOrderLocked), errors.Is(err, errno.ErrStaleFenceToken):
+		return nil, status.Error(codes.Aborted, "订单正在处理中，请稍后重试")
+	case err != nil:
+		logger.Ctx(ctx).Error("order.UpdateStatus failed", zap.Error(err))
+		return nil, status.Error(codes.Internal, "内部错误")
+	}
+	return &proto.Response{Success: true, Message: "success"}, nil
+}
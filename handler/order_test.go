@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"order_service/model"
+	"order_service/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateOrderRejectsInvalidItem(t *testing.T) {
+	tests := map[string]*proto.CreateOrderReq{
+		"no user":        {GoodsId: 1, Num: 1},
+		"no goods":       {UserId: 1, Num: 1},
+		"negative goods": {UserId: 1, GoodsId: -1, Num: 1},
+		"zero num":       {UserId: 1, GoodsId: 1},
+		"negative num":   {UserId: 1, GoodsId: 1, Num: -2},
+	}
+	for name, req := range tests {
+		// 参数无效时在调用业务层之前返回，不会用到未初始化的存储和下游服务
+		_, err := (&OrderSrv{}).CreateOrder(context.Background(), req)
+		if got := status.Code(err); got != codes.InvalidArgument {
+			t.Errorf("%s: code = %v, want InvalidArgument", name, got)
+		}
+	}
+}
+
+func TestUpdateOrderStatusRejectsLongReason(t *testing.T) {
+	// 按字符数限制，和 reason 列的 varchar(255) 一致
+	req := &proto.OrderStatus{OrderId: 1, Status: 5, Reason: strings.Repeat("退", model.MaxEventReasonLen+1)}
+	_, err := (&OrderSrv{}).UpdateOrderStatus(context.Background(), req)
+	if got := status.Code(err); got != codes.InvalidArgument {
+		t.Errorf("code = %v, want InvalidArgument", got)
+	}
+}
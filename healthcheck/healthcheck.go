@@ -0,0 +1,205 @@
+package healthcheck
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"order_service/config"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// 依赖健康检查
+// 定时探测 MySQL、Redis、RocketMQ 以及下游 goods/stock 服务，
+// 并把结果同步到 gRPC 健康检查服务上，供 consul 等外部系统使用。
+
+const (
+	_defaultInterval = 5 * time.Second
+	_defaultTimeout  = 2 * time.Second
+)
+
+// CheckFunc 单个依赖的探测函数，返回 nil 表示依赖可用
+type CheckFunc func(ctx context.Context) error
+
+// DependencyStatus 单个依赖的最近一次探测结果
+type DependencyStatus struct {
+	Name      string
+	Healthy   bool
+	Error     string
+	Latency   time.Duration
+	CheckedAt time.Time
+}
+
+type checker struct {
+	name string
+	fn   CheckFunc
+}
+
+var (
+	hs       *health.Server
+	services []string // 整体健康状态需要同步到的服务名（例如 proto.Order）
+
+	mu       sync.RWMutex
+	checkers []checker
+	results  = make(map[string]DependencyStatus)
+	stopped  bool
+)
+
+// Init 初始化健康检查模块
+// server 为注册到 gRPC 上的健康检查服务，serviceNames 为对外提供的服务名，
+// 所有依赖都可用时这些服务为 SERVING，否则为 NOT_SERVING。
+func Init(server *health.Server, serviceNames ...string) {
+	hs = server
+	services = append([]string{""}, serviceNames...) // "" 表示整个服务的健康状态
+	for _, name := range services {
+		hs.SetServingStatus(name, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	}
+}
+
+// Register 注册一个依赖的探测函数
+// 每个依赖在健康检查服务上也会有一个同名的服务状态，例如 "mysql"、"redis"
+func Register(name string, fn CheckFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+	checkers = append(checkers, checker{name: name, fn: fn})
+}
+
+// Start 启动定时探测，ctx 取消后退出
+// 启动时会先同步执行一次探测，保证服务注册到 consul 之前状态已经是准确的；
+// 探测间隔和超时每轮都从当前配置读取，配置热加载后下一轮生效
+func Start(ctx context.Context) {
+	interval, timeout := intervals(config.Get().HealthConfig)
+	checkAll(ctx, timeout)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+				interval, timeout = intervals(config.Get().HealthConfig)
+				checkAll(ctx, timeout)
+			}
+		}
+	}()
+}
+
+// intervals 返回探测间隔和超时时间，未配置时使用默认值
+func intervals(cfg *config.HealthConfig) (interval, timeout time.Duration) {
+	interval, timeout = _defaultInterval, _defaultTimeout
+	if cfg != nil && cfg.Interval > 0 {
+		interval = cfg.Interval
+	}
+	if cfg != nil && cfg.Timeout > 0 {
+		timeout = cfg.Timeout
+	}
+	return
+}
+
+// checkAll 并发探测所有依赖并更新健康状态
+func checkAll(ctx context.Context, timeout time.Duration) {
+	mu.RLock()
+	list := make([]checker, len(checkers))
+	copy(list, checkers)
+	mu.RUnlock()
+
+	var wg sync.WaitGroup
+	statuses := make([]DependencyStatus, len(list))
+	for i, c := range list {
+		wg.Add(1)
+		go func(i int, c checker) {
+			defer wg.Done()
+			statuses[i] = probe(ctx, c, timeout)
+		}(i, c)
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if stopped {
+		// 服务正在退出，保持 NOT_SERVING
+		return
+	}
+	allHealthy := true
+	for _, st := range statuses {
+		if prev, ok := results[st.Name]; ok && prev.Healthy != st.Healthy {
+			zap.L().Warn("dependency health changed",
+				zap.String("dependency", st.Name),
+				zap.Bool("healthy", st.Healthy),
+				zap.String("error", st.Error))
+		}
+		results[st.Name] = st
+		hs.SetServingStatus(st.Name, servingStatus(st.Healthy))
+		allHealthy = allHealthy && st.Healthy
+	}
+	for _, name := range services {
+		hs.SetServingStatus(name, servingStatus(allHealthy))
+	}
+}
+
+// probe 执行单个依赖的探测
+func probe(ctx context.Context, c checker, timeout time.Duration) DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.fn(ctx)
+	st := DependencyStatus{
+		Name:      c.name,
+		Healthy:   err == nil,
+		Latency:   time.Since(start),
+		CheckedAt: start,
+	}
+	if err != nil {
+		st.Error = err.Error()
+	}
+	return st
+}
+
+func servingStatus(healthy bool) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	if healthy {
+		return grpc_health_v1.HealthCheckResponse_SERVING
+	}
+	return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+}
+
+// Report 返回所有依赖最近一次的探测结果（按名称排序）
+func Report() []DependencyStatus {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := make([]DependencyStatus, 0, len(results))
+	for _, st := range results {
+		list = append(list, st)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Serving 返回服务当前是否处于可用状态
+func Serving() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	if stopped {
+		return false
+	}
+	for _, st := range results {
+		if !st.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// Shutdown 服务退出时调用，将所有服务状态置为 NOT_SERVING，
+// 让 consul 和客户端在服务真正停止前把流量摘掉
+func Shutdown() {
+	mu.Lock()
+	defer mu.Unlock()
+	stopped = true
+	if hs != nil {
+		hs.Shutdown()
+	}
+}
@@ -0,0 +1,38 @@
+package logger
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// 请求级别的 logger
+// 把带有 request_id、user_id、order_id、trace_id 等字段的 logger 放到 context 中，
+// biz/dao/consumer 中统一通过 logger.Ctx(ctx) 获取，日志就能按请求或订单串起来。
+
+type ctxKey struct{}
+
+// NewContext 在 ctx 已有 logger 的基础上追加字段，返回携带新 logger 的 ctx
+func NewContext(ctx context.Context, fields ...zap.Field) context.Context {
+	return context.WithValue(ctx, ctxKey{}, Ctx(ctx).With(fields...))
+}
+
+// Ctx 返回 ctx 中的 logger，没有时返回全局 logger
+func Ctx(ctx context.Context) *zap.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(ctxKey{}).(*zap.Logger); ok {
+			return l
+		}
+	}
+	return zap.L()
+}
+
+// TraceField 返回 ctx 中的 trace_id 字段，没有 trace 时返回 zap.Skip()
+func TraceField(ctx context.Context) zap.Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return zap.Skip()
+	}
+	return zap.String("trace_id", sc.TraceID().String())
+}
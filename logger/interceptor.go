@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDKey 请求ID在 gRPC metadata 中的 key，调用方未传时由服务端生成
+const RequestIDKey = "x-request-id"
+
+// userIDGetter / orderIDGetter 用于从请求中取出用户ID、订单ID
+// proto 生成的请求结构体只要有对应字段就会实现这两个接口
+type userIDGetter interface {
+	GetUserId() int64
+}
+
+type orderIDGetter interface {
+	GetOrderId() int64
+}
+
+// UnaryServerInterceptor 为每个请求创建带上下文字段的 logger 并放入 ctx，
+// 同时记录请求的耗时和返回码
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		requestID := requestIDFromContext(ctx)
+		grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, requestID))
+
+		fields := []zap.Field{
+			zap.String("request_id", requestID),
+			zap.String("method", info.FullMethod),
+			TraceField(ctx),
+		}
+		if r, ok := req.(userIDGetter); ok && r.GetUserId() > 0 {
+			fields = append(fields, zap.Int64("user_id", r.GetUserId()))
+		}
+		if r, ok := req.(orderIDGetter); ok && r.GetOrderId() > 0 {
+			fields = append(fields, zap.Int64("order_id", r.GetOrderId()))
+		}
+		ctx = NewContext(ctx, fields...)
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		l := Ctx(ctx).With(
+			zap.String("code", status.Code(err).String()),
+			zap.Duration("cost", time.Since(start)),
+		)
+		if err != nil {
+			l.Warn("rpc finished with error", zap.Error(err))
+		} else {
+			l.Debug("rpc finished")
+		}
+		return resp, err
+	}
+}
+
+// requestIDFromContext 从 metadata 中取请求ID，没有则生成一个
+func requestIDFromContext(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(RequestIDKey); len(ids) > 0 && ids[0] != "" {
+			return ids[0]
+		}
+	}
+	return uuid.NewString()
+}
@@ -0,0 +1,183 @@
+package logger
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 运行时日志级别控制
+// 全局级别使用 zap.AtomicLevel，可以随配置热加载或通过 Admin 接口修改；
+// 另外支持按包覆盖级别，例如排查问题时只把 biz/order 调成 debug。
+
+// modulePrefix 本项目包路径的前缀，按包覆盖时使用去掉前缀后的路径，例如 biz/order
+const modulePrefix = "order_service/"
+
+var (
+	level = zap.NewAtomicLevel()
+
+	pkgMu     sync.RWMutex
+	pkgLevels = map[string]zapcore.Level{}
+)
+
+// SetLevel 修改全局日志级别
+func SetLevel(text string) error {
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(text)); err != nil {
+		return err
+	}
+	level.SetLevel(l)
+	return nil
+}
+
+// SetPackageLevel 修改某个包的日志级别，text 为空时删除该包的覆盖配置
+// pkg 为去掉模块前缀的包路径，例如 biz/order，前缀匹配，biz 对 biz 下所有包生效
+func SetPackageLevel(pkg, text string) error {
+	pkg = strings.Trim(strings.TrimPrefix(pkg, modulePrefix), "/")
+	if len(pkg) == 0 {
+		return fmt.Errorf("package is required")
+	}
+	pkgMu.Lock()
+	defer pkgMu.Unlock()
+	if len(text) == 0 {
+		delete(pkgLevels, pkg)
+		return nil
+	}
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(text)); err != nil {
+		return err
+	}
+	pkgLevels[pkg] = l
+	return nil
+}
+
+// ResetPackageLevels 使用 levels 替换所有按包覆盖的级别（配置热加载时使用）
+func ResetPackageLevels(levels map[string]string) error {
+	parsed := make(map[string]zapcore.Level, len(levels))
+	for pkg, text := range levels {
+		var l zapcore.Level
+		if err := l.UnmarshalText([]byte(text)); err != nil {
+			return fmt.Errorf("invalid level %q for package %s: %w", text, pkg, err)
+		}
+		parsed[strings.Trim(strings.TrimPrefix(pkg, modulePrefix), "/")] = l
+	}
+	pkgMu.Lock()
+	pkgLevels = parsed
+	pkgMu.Unlock()
+	return nil
+}
+
+// Levels 返回当前的全局级别和按包覆盖的级别
+func Levels() (global string, packages map[string]string) {
+	pkgMu.RLock()
+	defer pkgMu.RUnlock()
+	packages = make(map[string]string, len(pkgLevels))
+	for pkg, l := range pkgLevels {
+		packages[pkg] = l.String()
+	}
+	return level.Level().String(), packages
+}
+
+// levelCore 根据全局级别和按包覆盖的级别过滤日志
+type levelCore struct {
+	zapcore.Core
+}
+
+func newLevelCore(core zapcore.Core) zapcore.Core {
+	return &levelCore{Core: core}
+}
+
+// Enabled 只要全局级别或任一包的覆盖级别允许，就返回 true，具体由 Check 判断
+func (c *levelCore) Enabled(l zapcore.Level) bool {
+	return l >= minLevel()
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields)}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !enabledFor(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// minLevel 全局级别和所有覆盖级别中最低的一个
+func minLevel() zapcore.Level {
+	min := level.Level()
+	pkgMu.RLock()
+	defer pkgMu.RUnlock()
+	for _, l := range pkgLevels {
+		if l < min {
+			min = l
+		}
+	}
+	return min
+}
+
+// enabledFor 判断当前调用方所在的包是否允许输出该级别的日志
+// 没有按包覆盖时直接使用全局级别，不会去取调用栈
+func enabledFor(l zapcore.Level) bool {
+	pkgMu.RLock()
+	empty := len(pkgLevels) == 0
+	pkgMu.RUnlock()
+	if empty {
+		return level.Enabled(l)
+	}
+
+	pkg := callerPackage()
+	pkgMu.RLock()
+	defer pkgMu.RUnlock()
+	if target, ok := matchPackage(pkg); ok {
+		return l >= target
+	}
+	return level.Enabled(l)
+}
+
+// matchPackage 按最长前缀查找包的覆盖级别，调用方需持有读锁
+func matchPackage(pkg string) (zapcore.Level, bool) {
+	keys := make([]string, 0, len(pkgLevels))
+	for k := range pkgLevels {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	for _, k := range keys {
+		if pkg == k || strings.HasPrefix(pkg, k+"/") {
+			return pkgLevels[k], true
+		}
+	}
+	return 0, false
+}
+
+// callerPackage 返回打日志的代码所在的包（去掉模块前缀），跳过 zap 和 logger 包自身
+func callerPackage() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		pkg := funcPackage(frame.Function)
+		if !strings.HasPrefix(pkg, "go.uber.org/zap") && pkg != modulePrefix+"logger" {
+			return strings.TrimPrefix(pkg, modulePrefix)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// funcPackage 从 runtime 的函数全名中取出包路径
+// 例如 order_service/biz/order.(*OrderEntity).ExecuteLocalTransaction -> order_service/biz/order
+func funcPackage(fn string) string {
+	slash := strings.LastIndex(fn, "/")
+	if dot := strings.Index(fn[slash+1:], "."); dot >= 0 {
+		return fn[:slash+1+dot]
+	}
+	return fn
+}
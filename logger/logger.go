@@ -3,6 +3,7 @@ package logger
 import (
 	"order_service/config"
 	"os"
+	"strings"
 
 	"github.com/natefinch/lumberjack"
 	"go.uber.org/zap"
@@ -18,23 +19,38 @@ var lg *zap.Logger
 func Init(cfg *config.LogConfig, mode string) (err error) {
 	writeSyncer := getLogWriter(cfg.Filename, cfg.MaxSize, cfg.MaxBackups, cfg.MaxAge)
 	encoder := getEncoder()
-	var l = new(zapcore.Level)
-	err = l.UnmarshalText([]byte(cfg.Level))
+	// 日志级别可以在运行时修改，见 level.go
+	err = SetLevel(cfg.Level)
 	if err != nil {
 		return
 	}
+	err = ResetPackageLevels(cfg.Packages)
+	if err != nil {
+		return
+	}
+	// error 及以上级别的日志单独在 xxx.err.log 记录一份
+	errFilename := cfg.ErrorFilename
+	if len(errFilename) == 0 {
+		errFilename = strings.TrimSuffix(cfg.Filename, ".log") + ".err.log"
+	}
+	errWriteSyncer := getLogWriter(errFilename, cfg.MaxSize, cfg.MaxBackups, cfg.MaxAge)
+	errCore := zapcore.NewCore(encoder, errWriteSyncer, zapcore.ErrorLevel)
+
 	var core zapcore.Core
 	if mode == "dev" {
 		// 进入开发模式，日志输出到终端
 		consoleEncoder := zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
 		core = zapcore.NewTee(
-			zapcore.NewCore(encoder, writeSyncer, l),
-			zapcore.NewCore(consoleEncoder, zapcore.Lock(os.Stdout), zapcore.DebugLevel),
+			newLevelCore(zapcore.NewCore(encoder, writeSyncer, zapcore.DebugLevel)),
+			errCore,
+			newLevelCore(zapcore.NewCore(consoleEncoder, zapcore.Lock(os.Stdout), zapcore.DebugLevel)),
 		)
 	} else {
-		core = zapcore.NewCore(encoder, writeSyncer, l)
+		core = zapcore.NewTee(
+			newLevelCore(zapcore.NewCore(encoder, writeSyncer, zapcore.DebugLevel)),
+			errCore,
+		)
 	}
-	// 复习回顾：日志默认输出到app.log，如何将err日志单独在 app.err.log 记录一份
 
 	lg = zap.New(core, zap.AddCaller()) // zap.AddCaller() 添加调用栈信息
 
@@ -43,6 +59,20 @@ func Init(cfg *config.LogConfig, mode string) (err error) {
 	return
 }
 
+// Reload 配置文件修改后重新设置日志级别
+// 通过 Admin 接口修改的级别会被配置文件中的值覆盖
+func Reload(cfg *config.LogConfig) error {
+	if err := SetLevel(cfg.Level); err != nil {
+		return err
+	}
+	if err := ResetPackageLevels(cfg.Packages); err != nil {
+		return err
+	}
+	global, packages := Levels()
+	zap.L().Info("log level reloaded", zap.String("level", global), zap.Any("packages", packages))
+	return nil
+}
+
 func getEncoder() zapcore.Encoder {
 	encoderConfig := zap.NewProductionEncoderConfig()
 	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
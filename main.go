@@ -1,26 +1,34 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net"
+	"order_service/auth"
+	"order_service/biz/coupon"
 	"order_service/biz/order"
+	"order_service/certs"
 	"order_service/config"
 	"order_service/dao/mq"
-	"order_service/dao/mysql"
 	"order_service/dao/redis"
+	"order_service/dao/store"
+	"order_service/gateway"
 	"order_service/handler"
+	"order_service/healthcheck"
 	"order_service/logger"
+	"order_service/metrics"
 	"order_service/proto"
+	"order_service/ratelimit"
 	"order_service/registry"
+	"order_service/rpc"
 	"order_service/third_party/snowflake"
+	"order_service/tracing"
 	"os"
 	"os/signal"
 	"syscall"
 
-	"github.com/apache/rocketmq-client-go/v2"
-	"github.com/apache/rocketmq-client-go/v2/consumer"
-	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
@@ -34,59 +42,121 @@ func main() {
 	flag.StringVar(&cfn, "conf", "./conf/config.yaml", "指定配置文件路径")
 	flag.Parse()
 
+	// 子命令，例如：order_service -conf="./conf/config.yaml" reconcile -dry-run
+	switch flag.Arg(0) {
+	case "reconcile":
+		os.Exit(runReconcile(cfn, flag.Args()[1:]))
+	case "migrate":
+		os.Exit(runMigrate(cfn, flag.Args()[1:]))
+	}
+
 	// 1. 加载配置文件
 	err := config.Init(cfn)
 	if err != nil {
-		panic(err) // 如果加载配置文件失败，直接退出程?
+		panic(err) // 如果加载配置文件失败，直接退出程序
 	}
 
-	// 2. 初始化日志模?
+	// 2. 初始化日志模块
 	err = logger.Init(config.Conf.LogConfig, config.Conf.Mode)
 	if err != nil {
-		panic(err) // 如果初始化日志模块失败，直接退出程?
+		panic(err) // 如果初始化日志模块失败，直接退出程序
+	}
+
+	// 配置文件修改后日志级别随之生效
+	config.Subscribe("logger", func(old, cur *config.SrvConfig) error {
+		return logger.Reload(cur.LogConfig)
+	})
+
+	// 初始化链路追踪
+	err = tracing.Init(config.Conf.TraceConfig, config.Conf.Name, config.Conf.Version)
+	if err != nil {
+		panic(err)
 	}
 
-	// 3. 初始? MySQL 数据库连?
-	err = mysql.Init(config.Conf.MySQLConfig)
+	// 3. 初始化数据库连接（MySQL 或 SQLite，见 storage.driver；配置了 sharding 时连接所有分片库）
+	err = store.Init(config.Conf)
+	if err != nil {
+		panic(err) // 如果初始化数据库失败，直接退出程序
+	}
+	// 表结构和模型不一致时直接退出，先执行 migrate up
+	err = store.Repo().CheckSchema(context.Background())
 	if err != nil {
-		panic(err) // 如果初始? MySQL 数据库失败，直接退出程?
+		panic(err)
 	}
 
-	// 初始? Redis 连接
+	// 初始化 Redis 连接
 	err = redis.Init(config.Conf.RedisConfig)
 	if err != nil {
-		panic(err) // 如果初始? Redis 失败，直接退出程?
+		panic(err) // 如果初始化 Redis 失败，直接退出程序
+	}
+	// 定价使用数据库中发放给用户的优惠券
+	coupon.Init()
+	// 接口鉴权，密钥修改后随配置热加载生效
+	err = auth.Init(config.Conf.AuthConfig)
+	if err != nil {
+		panic(err)
+	}
+	config.Subscribe("auth", func(old, cur *config.SrvConfig) error {
+		return auth.Init(cur.AuthConfig)
+	})
+	// 接口限流，规则修改后立即生效
+	ratelimit.Init(config.Conf.RateLimitConfig)
+	config.Subscribe("rate_limit", func(old, cur *config.SrvConfig) error {
+		ratelimit.Reload(cur.RateLimitConfig)
+		return nil
+	})
+	// 配置热加载：连接池大小等配置修改后立即生效
+	config.Subscribe("storage", store.Reload)
+	config.Subscribe("redis", func(old, cur *config.SrvConfig) error {
+		return redis.Reload(old.RedisConfig, cur.RedisConfig)
+	})
+
+	// 启动 Prometheus 指标服务，并采集数据库连接池指标
+	err = metrics.Init(config.Conf.MetricsConfig)
+	if err != nil {
+		panic(err)
+	}
+	for _, db := range store.Databases() {
+		sqlDB, err := db.SQLDB()
+		if err != nil {
+			panic(err)
+		}
+		err = metrics.RegisterDBStats(sqlDB, db.Name())
+		if err != nil {
+			panic(err)
+		}
 	}
-	// 6. 初始化snowflake
-	err = snowflake.Init(config.Conf.StartTime, config.Conf.MachineID)
+
+	// 6. 初始化snowflake，分库时订单号低位预留分片基因
+	err = snowflake.Init(config.Conf.StartTime, config.Conf.MachineID, config.Conf.ShardingConfig.GeneBits())
 	if err != nil {
 		panic(err)
 	}
 	// 7. 初始化rocketmq
-	err = mq.Init()
+	err = mq.Init(order.TxListener{})
 	if err != nil {
 		panic(err)
 	}
-	// 监听订单超时的消?
-	c, _ := rocketmq.NewPushConsumer(
-		consumer.WithGroupName("order_srv_1"),
-		consumer.WithNsResolver(primitive.NewPassthroughResolver([]string{"127.0.0.1:9876"})),
-	)
-	// 订阅topic
-	err = c.Subscribe("xx_pay_timeout", consumer.MessageSelector{}, order.OrderTimeouthandle)
+	// 加载 TLS 证书，gRPC 服务端和 goods/stock 客户端共用
+	err = certs.Init(config.Conf.TLSConfig, config.Conf.GoodsService.TLS || config.Conf.StockService.TLS)
+	if err != nil {
+		panic(err)
+	}
+	// 8. 初始化 goods/stock 服务客户端
+	err = rpc.InitSrvClient()
 	if err != nil {
-		fmt.Println(err.Error())
+		panic(err)
 	}
-	// Note: start after subscribe
-	err = c.Start()
+	// 监听订单超时的消息
+	err = mq.StartConsumer(config.Conf.RocketMqConfig.Topic.PayTimeOut, order.OrderTimeouthandle)
 	if err != nil {
 		panic(err)
 	}
 
-	err = registry.Init(config.Conf.ConsulConfig.Addr)
+	err = registry.Init(config.Conf.ConsulConfig.Addr, healthCheckTLS())
 	if err != nil {
 		zap.L().Error("Failed to initialize Consul", zap.Error(err))
-		// 可以选择退出或继续运行，取决于业务需?
+		// 可以选择退出或继续运行，取决于业务需求
 		panic(err)
 	}
 
@@ -96,11 +166,43 @@ func main() {
 		panic(err)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 证书文件变化后自动重新加载
+	go certs.StartReloader(ctx)
+
 	// 创建 gRPC 服务
-	s := grpc.NewServer()
-	// 注册健康检查服?
-	grpc_health_v1.RegisterHealthServer(s, health.NewServer())
+	s := grpc.NewServer(
+		grpc.Creds(certs.ServerCredentials()),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			metrics.UnaryServerInterceptor(),
+			logger.UnaryServerInterceptor(),
+			certs.UnaryServerInterceptor(),
+			auth.UnaryServerInterceptor(),
+			ratelimit.UnaryServerInterceptor(),
+			store.UnaryServerInterceptor(),
+		),
+	)
+	// 注册健康检查服务，健康状态由 healthcheck 根据依赖的探测结果维护
+	hs := health.NewServer()
+	grpc_health_v1.RegisterHealthServer(s, hs)
 	proto.RegisterOrderServer(s, &handler.OrderSrv{})
+	proto.RegisterAdminServer(s, &handler.AdminSrv{})
+
+	healthcheck.Init(hs, proto.Order_ServiceDesc.ServiceName)
+	healthcheck.Register(config.Conf.StorageConfig.DriverName(), store.Ping)
+	healthcheck.Register("redis", redis.Ping)
+	healthcheck.Register("rocketmq", mq.Ping)
+	healthcheck.Register(config.Conf.GoodsService.Name, rpc.PingGoods)
+	healthcheck.Register(config.Conf.StockService.Name, rpc.PingStock)
+	healthcheck.Start(ctx)
+
+	// 库存台账对账、订单-库存对账、冷订单归档
+	go order.StartLedgerReconciler(ctx)
+	go order.StartStockReconciler(ctx)
+	go order.StartArchiver(ctx)
 
 	// 启动 gRPC 服务
 	go func() {
@@ -110,11 +212,18 @@ func main() {
 		}
 	}()
 
-	// 注册服务? Consul
+	// 启动 HTTP/JSON 网关
+	err = gateway.Init(config.Conf.GatewayConfig, fmt.Sprintf("127.0.0.1:%d", config.Conf.Port),
+		certs.ClientCredentials(certs.Enabled(), tlsServerName()), certs.GatewayTLSConfig())
+	if err != nil {
+		panic(err)
+	}
+
+	// 注册服务到 Consul
 	err = registry.Reg.RegisterService(config.Conf.Name, config.Conf.IP, config.Conf.Port, nil)
 	if err != nil {
 		zap.L().Error("Failed to register service to Consul", zap.Error(err))
-		// 可以选择退出或继续运行，取决于业务需?
+		// 可以选择退出或继续运行，取决于业务需求
 		panic(err)
 
 	}
@@ -126,11 +235,42 @@ func main() {
 	)
 
 	// 服务退出时注销服务
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
-	<-quit // 等待退出信?
+	<-quit // 等待退出信号
+
+	// 先把健康状态置为 NOT_SERVING，让调用方不再把新请求发过来
+	healthcheck.Shutdown()
 
 	// 注销服务
 	serviceId := fmt.Sprintf("%s-%s-%d", config.Conf.Name, config.Conf.IP, config.Conf.Port)
 	registry.Reg.Deregister(serviceId)
+
+	// 等待处理中的请求完成后再退出
+	gateway.Exit()
+	metrics.Exit()
+	tracing.Exit(context.Background())
+	s.GracefulStop()
+	// 请求处理完后再停止消费和关闭生产者
+	mq.Exit()
+	store.Close()
+}
+
+// tlsServerName 本服务证书中的名称，没有配置时使用服务名
+func tlsServerName() string {
+	if config.Conf.TLSConfig != nil && len(config.Conf.TLSConfig.ServerName) > 0 {
+		return config.Conf.TLSConfig.ServerName
+	}
+	return config.Conf.Name
+}
+
+// healthCheckTLS gRPC 服务开启 TLS 时 Consul 健康检查也使用 TLS
+func healthCheckTLS() *registry.HealthCheckTLS {
+	if !certs.Enabled() {
+		return nil
+	}
+	return &registry.HealthCheckTLS{
+		ServerName: tlsServerName(),
+		SkipVerify: config.Conf.TLSConfig.HealthCheckSkipVerify,
+	}
 }
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	serverHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_server_handled_total",
+		Help:      "Number of RPCs completed on the server, by method and code.",
+	}, []string{"method", "code"})
+	serverHandlingSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_server_handling_seconds",
+		Help:      "Latency of RPCs handled by the server, by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	clientHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_handled_total",
+		Help:      "Number of RPCs completed by the client, by method and code.",
+	}, []string{"method", "code"})
+	clientHandlingSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_handling_seconds",
+		Help:      "Latency of RPCs issued by the client, by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+// UnaryServerInterceptor 统计服务端每个方法的耗时和返回码
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		serverHandlingSeconds.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		serverHandled.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		return resp, err
+	}
+}
+
+// UnaryClientInterceptor 统计调用下游服务每个方法的耗时和返回码
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		clientHandlingSeconds.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		clientHandled.WithLabelValues(method, status.Code(err).String()).Inc()
+		return err
+	}
+}
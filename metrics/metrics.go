@@ -0,0 +1,215 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"order_service/config"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// Prometheus 监控指标
+// 通过独立的 HTTP 端口暴露 /metrics，供 Prometheus 抓取。
+
+const namespace = "order_srv"
+
+var (
+	// OrdersCreated 创建成功的订单数
+	OrdersCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_created_total",
+		Help:      "Number of orders created successfully.",
+	})
+	// OrdersFailed 创建失败的订单数，reason 为失败环节
+	OrdersFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_failed_total",
+		Help:      "Number of orders failed to create, by reason.",
+	}, []string{"reason"})
+	// OrdersTimedOut 支付超时被关闭的订单数，source 为处理来源（consumer/scanner）
+	OrdersTimedOut = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_timed_out_total",
+		Help:      "Number of orders closed because of payment timeout, by source.",
+	}, []string{"source"})
+	// OrdersRolledBack 回滚库存的订单数，source 为触发回滚的来源
+	OrdersRolledBack = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_rolled_back_total",
+		Help:      "Number of orders whose stock was rolled back, by source.",
+	}, []string{"source"})
+
+	// TxMessageDuration 事务消息（SendMessageInTransaction）的耗时，state 为最终的事务状态
+	TxMessageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "tx_message_duration_seconds",
+		Help:      "Latency of SendMessageInTransaction, by final transaction state.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"topic", "state"})
+
+	// ConsumeLag 消息从投递时间到被消费的延迟
+	ConsumeLag = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mq_consume_lag_seconds",
+		Help:      "Delay between a message becoming deliverable and being consumed.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 600},
+	}, []string{"topic"})
+	// ConsumeRetries 重新投递的消息数
+	ConsumeRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mq_consume_retries_total",
+		Help:      "Number of redelivered messages consumed.",
+	}, []string{"topic"})
+	// ConsumeResults 消息消费结果
+	ConsumeResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mq_consume_total",
+		Help:      "Number of consumed messages, by result (success/retry/dead_letter).",
+	}, []string{"topic", "result"})
+
+	// CacheRequests 订单缓存的查询次数，result 为 hit/miss/error
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Number of order cache lookups, by cache and result (hit/miss/error).",
+	}, []string{"cache", "result"})
+
+	// StockRollbackSkipped 库存台账显示已经回滚过、被跳过的回滚次数
+	StockRollbackSkipped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stock_rollback_skipped_total",
+		Help:      "Number of stock rollbacks skipped because the ledger shows they were already done, by source.",
+	}, []string{"source"})
+	// StockLedgerDrift 最近一次对账发现的库存台账与订单状态不一致的记录数
+	StockLedgerDrift = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "stock_ledger_drift",
+		Help:      "Number of stock ledger entries inconsistent with order status found by the last reconciliation, by kind.",
+	}, []string{"kind"})
+	// StockReconcileOrphans 订单-库存对账发现的孤儿扣减数，action 为处理结果
+	StockReconcileOrphans = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stock_reconcile_orphans_total",
+		Help:      "Number of stock deductions without an order found by reconciliation, by action.",
+	}, []string{"action"})
+
+	// CouponOperations 优惠券锁定/核销/释放次数，result 为 ok/conflict/error
+	CouponOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "coupon_operations_total",
+		Help:      "Number of coupon operations, by op (reserve/confirm/release) and result (ok/conflict/error).",
+	}, []string{"op", "result"})
+
+	// OrdersArchived 移动到归档表的订单数
+	OrdersArchived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_archived_total",
+		Help:      "Number of orders moved to archive tables, by database.",
+	}, []string{"database"})
+
+	// ReplicaLag 从库最近一次检查时的复制延迟
+	ReplicaLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "db_replica_lag_seconds",
+		Help:      "Replication lag of read replicas at the last check.",
+	}, []string{"database", "replica"})
+	// ReplicaAvailable 从库是否可用（1 可用，0 延迟过大、复制中断或连接失败，查询改读主库）
+	ReplicaAvailable = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "db_replica_available",
+		Help:      "Whether a read replica is used for reads (1) or skipped because of lag or errors (0).",
+	}, []string{"database", "replica"})
+
+	// TLSCertExpiry 当前使用的证书的过期时间
+	TLSCertExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tls_cert_expiry_timestamp_seconds",
+		Help:      "Expiry time of the TLS certificate currently in use, in unix seconds.",
+	})
+	// TLSReloads 证书重新加载的次数
+	TLSReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tls_reloads_total",
+		Help:      "Number of TLS certificate reloads, by result.",
+	}, []string{"result"})
+
+	// RateLimited 被限流拒绝的请求数，limiter 为 global/user/goods
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Number of requests rejected by rate limiting, by method and limiter (global/user/goods).",
+	}, []string{"method", "limiter"})
+)
+
+var srv *http.Server
+
+func init() {
+	prometheus.MustRegister(
+		OrdersCreated,
+		OrdersFailed,
+		OrdersTimedOut,
+		OrdersRolledBack,
+		TxMessageDuration,
+		ConsumeLag,
+		ConsumeRetries,
+		ConsumeResults,
+		CacheRequests,
+		StockRollbackSkipped,
+		StockLedgerDrift,
+		StockReconcileOrphans,
+		CouponOperations,
+		OrdersArchived,
+		ReplicaLag,
+		ReplicaAvailable,
+		TLSCertExpiry,
+		TLSReloads,
+		RateLimited,
+		serverHandled,
+		serverHandlingSeconds,
+		clientHandled,
+		clientHandlingSeconds,
+	)
+}
+
+// Init 启动 /metrics HTTP 服务，cfg.Port 为 0 时不启动
+func Init(cfg *config.MetricsConfig) error {
+	if cfg == nil || cfg.Port == 0 {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv = &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.Port),
+		Handler: mux,
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			zap.L().Error("metrics ListenAndServe failed", zap.Error(err))
+		}
+	}()
+	zap.L().Info("metrics server start", zap.Int("port", cfg.Port))
+	return nil
+}
+
+// Exit 关闭 /metrics HTTP 服务
+func Exit() error {
+	if srv == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+// RegisterDBStats 注册数据库连接池指标（打开/空闲/使用中连接数、等待次数等）
+func RegisterDBStats(db *sql.DB, dbName string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, dbName))
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"order_service/config"
+	"order_service/dao/orm"
+	"order_service/dao/store"
+	"order_service/logger"
+)
+
+// runMigrate migrate 子命令：管理数据库表结构迁移
+//
+//	migrate up [n]      执行未执行的迁移，不指定 n 时全部执行
+//	migrate down [n]    回退最近 n 个迁移，默认 1 个
+//	migrate status      以 JSON 格式输出每个迁移的执行状态
+//	migrate force <v>   迁移失败并人工修复后，把数据库标记为已执行到 v 版本
+func runMigrate(cfn string, args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: order_service [-conf file] migrate up [n] | down [n] | status | force <version>")
+	}
+	fs.Parse(args)
+
+	action, n := fs.Arg(0), int64(0)
+	if len(fs.Arg(1)) > 0 {
+		v, err := strconv.ParseInt(fs.Arg(1), 10, 64)
+		if err != nil || v < 0 {
+			fmt.Fprintf(os.Stderr, "invalid number: %s\n", fs.Arg(1))
+			return 1
+		}
+		n = v
+	}
+	switch action {
+	case "up", "status":
+	case "down":
+		if len(fs.Arg(1)) == 0 {
+			n = 1
+		}
+	case "force":
+		if len(fs.Arg(1)) == 0 {
+			fs.Usage()
+			return 1
+		}
+	default:
+		fs.Usage()
+		return 1
+	}
+
+	if err := config.Init(cfn); err != nil {
+		fmt.Fprintf(os.Stderr, "load config failed, err:%v\n", err)
+		return 1
+	}
+	if err := logger.Init(config.Conf.LogConfig, config.Conf.Mode); err != nil {
+		fmt.Fprintf(os.Stderr, "init logger failed, err:%v\n", err)
+		return 1
+	}
+	if err := store.Init(config.Conf); err != nil {
+		fmt.Fprintf(os.Stderr, "init storage failed, err:%v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	var (
+		done []orm.Migration
+		err  error
+	)
+	switch action {
+	case "up":
+		done, err = store.Repo().MigrateUp(ctx, int(n))
+	case "down":
+		done, err = store.Repo().MigrateDown(ctx, int(n))
+	case "force":
+		err = store.Repo().ForceMigration(ctx, n)
+	case "status":
+		states, errStatus := store.Repo().MigrationStatus(ctx)
+		if errStatus != nil {
+			fmt.Fprintf(os.Stderr, "migrate status failed, err:%v\n", errStatus)
+			return 1
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(states)
+		return 0
+	}
+	for _, mg := range done {
+		fmt.Printf("%s %d_%s\n", action, mg.Version, mg.Name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate %s failed, err:%v\n", action, err)
+		return 1
+	}
+	return 0
+}
@@ -5,13 +5,13 @@ import (
 )
 
 type BaseModel struct {
-	ID       uint      `gorm:"primaryKey"`
-	CreateAt time.Time `gorm:"autoCreateTime"`   // 创建时间
-	UpdateAt time.Time `gorm:"autoUpdateTime"`   // 更新时间
-	CreateBy string    `gorm:"column:create_by"` // 指定数据库中的列名
-	UpdateBy string
-	Version  int16
-	isDel    int8 `gorm:"index"`
+	ID       uint       `gorm:"column:id;primaryKey"`
+	CreateAt time.Time  `gorm:"column:create_at;autoCreateTime"` // 创建时间
+	UpdateAt time.Time  `gorm:"column:update_at;autoUpdateTime"` // 更新时间
+	CreateBy string     `gorm:"column:create_by"`                // 指定数据库中的列名
+	UpdateBy string     `gorm:"column:update_by"`
+	Version  int16      `gorm:"column:version"`
+	IsDel    DeleteFlag `gorm:"column:is_del;index"` // 软删除标记，见 soft_delete.go
 }
 
 // OrderGoodsStockInfo 订单商品信息
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// 优惠券状态
+const (
+	CouponStatusUnused   = "unused"   // 未使用
+	CouponStatusReserved = "reserved" // 下单时锁定，等待支付
+	CouponStatusUsed     = "used"     // 已支付，已核销
+)
+
+// Coupon 发放给用户的优惠券，每张券只能使用一次
+type Coupon struct {
+	BaseModel             // 嵌入默认的7个字段：ID、创建时间、更新时间、创建者、更新者、版本号、是否删除
+	Code        string    `gorm:"column:code;type:varchar(64);not_null;uniqueIndex"`        // 券码
+	UserId      int64     `gorm:"column:user_id;type:bigint(20);not_null;index"`            // 券所属的用户
+	Type        string    `gorm:"column:type;type:varchar(16);not_null"`                    // amount 立减，percent 折扣
+	Value       int64     `gorm:"column:value;type:bigint(20);not_null"`                    // 立减金额（分），或者折扣百分比
+	MinAmount   int64     `gorm:"column:min_amount;type:bigint(20);not_null;default:0"`     // 使用门槛（分）
+	MaxDiscount int64     `gorm:"column:max_discount;type:bigint(20);not_null;default:0"`   // 折扣券最高减免（分），0 表示不限制
+	Status      string    `gorm:"column:status;type:varchar(16);not_null;default:'unused'"` // 状态
+	OrderId     int64     `gorm:"column:order_id;type:bigint(20);not_null;default:0;index"` // 使用该券的订单
+	ExpireAt    time.Time `gorm:"column:expire_at;not_null"`                                // 过期时间
+}
+
+func (Coupon) TableName() string {
+	return "xx_coupon"
+}
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// 订单状态变化的来源
+const (
+	EventSourceCreate          = "create_order"     // 创建订单
+	EventSourceCreateFailed    = "create_failed"    // 订单写入后创建流程失败，取消订单
+	EventSourceRPC             = "rpc"              // UpdateOrderStatus 接口
+	EventSourceTimeoutConsumer = "timeout_consumer" // 超时消息消费者
+	EventSourceTimeoutScanner  = "timeout_scanner"  // 超时扫描任务
+)
+
+// MaxEventReasonLen 订单事件中原因的最大长度（字符数），和 reason 列的 varchar(255) 一致
+const MaxEventReasonLen = 255
+
+// StatusChange 修改订单状态时说明变化的来源和原因，和操作者一起写入订单事件表
+type StatusChange struct {
+	Source string // 来源，取值见 EventSource*
+	Reason string // 原因，可以为空，最多 MaxEventReasonLen 个字符
+}
+
+// OrderEvent 订单状态变化记录，只追加不修改，和订单状态在同一个事务中写入
+// 订单归档时不移动，归档后仍然可以查询订单的历史
+type OrderEvent struct {
+	ID         uint      `gorm:"primaryKey"`
+	CreateAt   time.Time `gorm:"autoCreateTime"`                                      // 状态变化的时间
+	OrderId    int64     `gorm:"column:order_id;type:bigint(20);not_null"`            // 订单ID
+	UserId     int64     `gorm:"column:user_id;type:bigint(20);not_null"`             // 订单所属的用户
+	FromStatus string    `gorm:"column:from_status;type:varchar(16);not_null"`        // 变化前的状态，创建订单时为空
+	ToStatus   string    `gorm:"column:to_status;type:varchar(16);not_null"`          // 变化后的状态
+	Actor      string    `gorm:"column:actor;type:varchar(64);not_null"`              // 操作者，见 auth.Caller
+	Source     string    `gorm:"column:source;type:varchar(32);not_null"`             // 来源，取值见 EventSource*
+	Reason     string    `gorm:"column:reason;type:varchar(255);not_null;default:''"` // 原因
+}
+
+func (OrderEvent) TableName() string {
+	return "xx_order_event"
+}
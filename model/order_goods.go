@@ -1,16 +1,24 @@
 package model
 
 type OrderDetail struct {
-	BaseModel        // 嵌入默认的7个字段：ID、创建时间、更新时间、创建者、更新者、版本号、是否删除
-	OrderId   int64  `gorm:"column:order_id;type:bigint(20);not_null"`             // 订单ID，关联的订单。
-	GoodsId   int64  `gorm:"column:goods_id;type:bigint(20);not_null"`             // 商品ID，订单中包含的商品。
-	UserId    int64  `gorm:"column:user_id;type:bigint(20);not_null"`              // 用户ID，订单所属的用户。
-	Num       int64  `gorm:"column:num;type:bigint(20);not_null"`                  // 商品数量，用户购买的商品数量。
-	Title     string `gorm:"column:title;type:varchar(255);not_null;default:''"`   // 商品名称，商品的标题。
-	Status    string `gorm:"column:title;type:varchar(255);not_null;default:''"`   //支付状态
-	Price     int64  `gorm:"column:price;type:bigint(20);not_null;default:0"`      // 销售价格（单位：分）。
-	Brief     string `gorm:"column:brief;type:varchar(255);not_null;default:''"`   // 商品简介，商品的简要描述。
-	PayAmount int64  `gorm:"column:pay_amount;type:bigint(20);not_null;default:0"` // 支付金额（单位：分），实际支付的金额。
+	BaseModel         // 嵌入默认的7个字段：ID、创建时间、更新时间、创建者、更新者、版本号、是否删除
+	OrderId    int64  `gorm:"column:order_id;type:bigint(20);not_null"`                  // 订单ID，关联的订单。
+	GoodsId    int64  `gorm:"column:goods_id;type:bigint(20);not_null"`                  // 商品ID，订单中包含的商品。
+	UserId     int64  `gorm:"column:user_id;type:bigint(20);not_null"`                   // 用户ID，订单所属的用户。
+	Num        int64  `gorm:"column:num;type:bigint(20);not_null"`                       // 商品数量，用户购买的商品数量。
+	Title      string `gorm:"column:title;type:varchar(255);not_null;default:''"`        // 商品名称，商品的标题。
+	Status     string `gorm:"column:status;type:varchar(16);not_null;default:'pending'"` // 订单状态，取值见 order_status.go
+	Price      int64  `gorm:"column:price;type:bigint(20);not_null;default:0"`           // 销售价格（单位：分）。
+	Brief      string `gorm:"column:brief;type:varchar(255);not_null;default:''"`        // 商品简介，商品的简要描述。
+	PayAmount  int64  `gorm:"column:pay_amount;type:bigint(20);not_null;default:0"`      // 支付金额（单位：分），实际支付的金额。
+	FenceToken int64  `gorm:"column:fence_token;type:bigint(20);not_null;default:0"`     // 最近一次修改订单状态时持有的订单锁 fencing token
+
+	// 价格明细（单位：分），实付金额 = 小计 - 满减 - 优惠券 + 运费
+	Subtotal          int64  `gorm:"column:subtotal;type:bigint(20);not_null;default:0"`           // 小计（单价 × 数量）
+	PromotionDiscount int64  `gorm:"column:promotion_discount;type:bigint(20);not_null;default:0"` // 满减金额
+	CouponCode        string `gorm:"column:coupon_code;type:varchar(64);not_null;default:''"`      // 使用的优惠券
+	CouponDiscount    int64  `gorm:"column:coupon_discount;type:bigint(20);not_null;default:0"`    // 优惠券减免金额
+	ShippingFee       int64  `gorm:"column:shipping_fee;type:bigint(20);not_null;default:0"`       // 运费
 }
 
 func (OrderDetail) TableName() string {
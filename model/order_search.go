@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// OrderFilter 运维后台跨分片查询订单的条件，零值表示不按该条件过滤
+type OrderFilter struct {
+	UserId        int64
+	Statuses      []string  // 订单状态，满足其中任意一个
+	CreatedAfter  time.Time // 创建时间不早于
+	CreatedBefore time.Time // 创建时间早于
+}
+
+// OrderNewerThan 查询结果的排序：创建时间倒序，创建时间相同时按订单号倒序
+// 分片之间的自增ID互不相关，合并多个分片的结果时只能按创建时间和订单号排序
+func OrderNewerThan(a, b Order) bool {
+	if !a.CreateAt.Equal(b.CreateAt) {
+		return a.CreateAt.After(b.CreateAt)
+	}
+	return a.OrderId > b.OrderId
+}
@@ -0,0 +1,83 @@
+package model
+
+// 订单状态
+// 数据库中以字符串保存，对外接口（proto.OrderInfo.Status）使用数字编码
+const (
+	OrderStatusPending   = "pending"   // 创建成功，待支付
+	OrderStatusUnpaid    = "unpaid"    // 待支付
+	OrderStatusPaid      = "paid"      // 已支付
+	OrderStatusShipped   = "shipped"   // 已发货
+	OrderStatusCompleted = "completed" // 已完成
+	OrderStatusCancelled = "cancelled" // 已取消
+	OrderStatusTimeout   = "timeout"   // 支付超时
+)
+
+// statusCodes 订单状态与对外数字编码的对应关系
+var statusCodes = map[string]int32{
+	OrderStatusPending:   1,
+	OrderStatusUnpaid:    1,
+	OrderStatusPaid:      2,
+	OrderStatusShipped:   3,
+	OrderStatusCompleted: 4,
+	OrderStatusCancelled: 5,
+	OrderStatusTimeout:   6,
+}
+
+// StatusCode 订单状态转换为对外的数字编码，未知状态返回 0
+func StatusCode(status string) int32 {
+	return statusCodes[status]
+}
+
+// StatusFromCode 对外的数字编码转换为订单状态，ok 为 false 表示编码无效
+func StatusFromCode(code int32) (status string, ok bool) {
+	switch code {
+	case 1:
+		return OrderStatusUnpaid, true
+	case 2:
+		return OrderStatusPaid, true
+	case 3:
+		return OrderStatusShipped, true
+	case 4:
+		return OrderStatusCompleted, true
+	case 5:
+		return OrderStatusCancelled, true
+	case 6:
+		return OrderStatusTimeout, true
+	}
+	return "", false
+}
+
+// IsFinalStatus 订单是否已经结束（已完成、已取消、支付超时），结束的订单状态不会再变化
+func IsFinalStatus(status string) bool {
+	switch status {
+	case OrderStatusCompleted, OrderStatusCancelled, OrderStatusTimeout:
+		return true
+	}
+	return false
+}
+
+// statusTransitions 订单状态可以变化到的状态，已完成、已取消、支付超时的订单不能再变化
+var statusTransitions = map[string][]string{
+	OrderStatusPending: {OrderStatusUnpaid, OrderStatusPaid, OrderStatusCancelled, OrderStatusTimeout},
+	OrderStatusUnpaid:  {OrderStatusPaid, OrderStatusCancelled, OrderStatusTimeout},
+	OrderStatusPaid:    {OrderStatusShipped, OrderStatusCompleted},
+	OrderStatusShipped: {OrderStatusCompleted},
+}
+
+// CanTransition 订单状态是否可以从 from 变为 to，状态不变时返回 true（重复请求）
+func CanTransition(from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, s := range statusTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUnpaidStatus 订单是否待支付
+func IsUnpaidStatus(status string) bool {
+	return status == OrderStatusPending || status == OrderStatusUnpaid
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 软删除
+// 订单相关的表用 is_del 列标记删除（0 正常，1 已删除），和 gorm.DeletedAt 的用法一致：
+//   - 查询、更新自动加上 is_del = 0 条件，需要包含已删除数据时使用 Unscoped()
+//   - Delete 改为 UPDATE ... SET is_del = 1，Unscoped().Delete 才会真正删除
+
+// DeleteFlag 软删除标记
+type DeleteFlag int8
+
+const (
+	NotDeleted DeleteFlag = 0
+	Deleted    DeleteFlag = 1
+)
+
+func (DeleteFlag) QueryClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{softDeleteQueryClause{Field: f}}
+}
+
+func (DeleteFlag) UpdateClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{softDeleteUpdateClause{Field: f}}
+}
+
+func (DeleteFlag) DeleteClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{softDeleteDeleteClause{Field: f}}
+}
+
+// softDeleteQueryClause 查询时排除已删除的数据
+type softDeleteQueryClause struct {
+	Field *schema.Field
+}
+
+func (sd softDeleteQueryClause) Name() string {
+	return ""
+}
+
+func (sd softDeleteQueryClause) Build(clause.Builder) {
+}
+
+func (sd softDeleteQueryClause) MergeClause(*clause.Clause) {
+}
+
+func (sd softDeleteQueryClause) ModifyStatement(stmt *gorm.Statement) {
+	if _, ok := stmt.Clauses["soft_delete_enabled"]; ok || stmt.Statement.Unscoped {
+		return
+	}
+	// 已有的条件中只有一个 OR 时先整体用括号包起来，否则 is_del 条件只对 OR 的一侧生效
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) >= 1 {
+			for _, expr := range where.Exprs {
+				if orCond, ok := expr.(clause.OrConditions); ok && len(orCond.Exprs) == 1 {
+					where.Exprs = []clause.Expression{clause.And(where.Exprs...)}
+					c.Expression = where
+					stmt.Clauses["WHERE"] = c
+					break
+				}
+			}
+		}
+	}
+
+	stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: sd.Field.DBName}, Value: NotDeleted},
+	}})
+	stmt.Clauses["soft_delete_enabled"] = clause.Clause{}
+}
+
+// softDeleteUpdateClause 更新时不修改已删除的数据
+type softDeleteUpdateClause struct {
+	Field *schema.Field
+}
+
+func (sd softDeleteUpdateClause) Name() string {
+	return ""
+}
+
+func (sd softDeleteUpdateClause) Build(clause.Builder) {
+}
+
+func (sd softDeleteUpdateClause) MergeClause(*clause.Clause) {
+}
+
+func (sd softDeleteUpdateClause) ModifyStatement(stmt *gorm.Statement) {
+	if stmt.SQL.Len() == 0 && !stmt.Statement.Unscoped {
+		softDeleteQueryClause(sd).ModifyStatement(stmt)
+	}
+}
+
+// softDeleteDeleteClause 删除改为设置 is_del = 1
+type softDeleteDeleteClause struct {
+	Field *schema.Field
+}
+
+func (sd softDeleteDeleteClause) Name() string {
+	return ""
+}
+
+func (sd softDeleteDeleteClause) Build(clause.Builder) {
+}
+
+func (sd softDeleteDeleteClause) MergeClause(*clause.Clause) {
+}
+
+func (sd softDeleteDeleteClause) ModifyStatement(stmt *gorm.Statement) {
+	if stmt.SQL.Len() > 0 || stmt.Statement.Unscoped {
+		return
+	}
+	stmt.AddClause(clause.Set{{Column: clause.Column{Name: sd.Field.DBName}, Value: Deleted}})
+	stmt.SetColumn(sd.Field.DBName, Deleted, true)
+
+	if stmt.Schema != nil {
+		_, queryValues := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields)
+		column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+		if len(values) > 0 {
+			stmt.AddClause(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
+		}
+	}
+
+	softDeleteQueryClause(sd).ModifyStatement(stmt)
+	stmt.AddClauseIfNotExists(clause.Update{})
+	stmt.Build(stmt.DB.Callback().Update().Clauses...)
+}
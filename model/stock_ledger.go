@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// 库存台账状态
+const (
+	LedgerStatusDeducted    = "deducted"     // 已扣减库存
+	LedgerStatusRollingBack = "rolling_back" // 正在回滚，回滚失败会恢复为 deducted
+	LedgerStatusRolledBack  = "rolled_back"  // 已回滚
+)
+
+// StockLedger 库存补偿台账，每个订单的每个商品一条记录
+// 扣减库存成功后写入，回滚库存前通过条件更新抢占记录，保证同一笔扣减只回滚一次
+type StockLedger struct {
+	ID       uint      `gorm:"primaryKey"`
+	CreateAt time.Time `gorm:"autoCreateTime"`                                                      // 创建时间
+	UpdateAt time.Time `gorm:"autoUpdateTime"`                                                      // 更新时间
+	OrderId  int64     `gorm:"column:order_id;type:bigint(20);not_null;uniqueIndex:uk_order_goods"` // 订单ID
+	GoodsId  int64     `gorm:"column:goods_id;type:bigint(20);not_null;uniqueIndex:uk_order_goods"` // 商品ID
+	Num      int64     `gorm:"column:num;type:bigint(20);not_null"`                                 // 扣减的数量
+	Status   string    `gorm:"column:status;type:varchar(16);not_null"`                             // 台账状态
+	Source   string    `gorm:"column:source;type:varchar(32);not_null;default:''"`                  // 最近一次回滚的来源
+}
+
+func (StockLedger) TableName() string {
+	return "xx_stock_ledger"
+}
+
+// 台账与订单状态不一致的类型
+const (
+	DriftMissingRollback    = "missing_rollback"    // 订单已超时/取消，库存未回滚
+	DriftUnexpectedRollback = "unexpected_rollback" // 库存已回滚，订单仍然有效
+	DriftStuckRollback      = "stuck_rollback"      // 回滚长时间没有完成
+	DriftOrphanDeduction    = "orphan_deduction"    // 扣减了库存，订单没有创建成功
+	DriftMissingLedger      = "missing_ledger"      // 订单没有扣减记录
+)
+
+// LedgerDrift 台账与订单状态不一致的记录
+type LedgerDrift struct {
+	Kind         string
+	OrderId      int64
+	GoodsId      int64
+	LedgerStatus string
+	OrderStatus  string
+}
@@ -0,0 +1,407 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v3.20.1
+// source: admin.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Admin_DependencyReport_FullMethodName = "/proto.Admin/DependencyReport"
+	Admin_GetLogLevel_FullMethodName      = "/proto.Admin/GetLogLevel"
+	Admin_SetLogLevel_FullMethodName      = "/proto.Admin/SetLogLevel"
+	Admin_GetArchiveStatus_FullMethodName = "/proto.Admin/GetArchiveStatus"
+	Admin_SetArchivePaused_FullMethodName = "/proto.Admin/SetArchivePaused"
+	Admin_DeleteOrder_FullMethodName      = "/proto.Admin/DeleteOrder"
+	Admin_RestoreOrder_FullMethodName     = "/proto.Admin/RestoreOrder"
+	Admin_SearchOrders_FullMethodName     = "/proto.Admin/SearchOrders"
+)
+
+// AdminClient is the client API for Admin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Admin 运维管理服务
+type AdminClient interface {
+	// 查询依赖健康状态
+	DependencyReport(ctx context.Context, in *DependencyReportReq, opts ...grpc.CallOption) (*DependencyReportResp, error)
+	// 查询日志级别
+	GetLogLevel(ctx context.Context, in *GetLogLevelReq, opts ...grpc.CallOption) (*LogLevelResp, error)
+	// 修改日志级别，package 为空时修改全局级别
+	SetLogLevel(ctx context.Context, in *SetLogLevelReq, opts ...grpc.CallOption) (*LogLevelResp, error)
+	// 查询冷订单归档任务的状态
+	GetArchiveStatus(ctx context.Context, in *GetArchiveStatusReq, opts ...grpc.CallOption) (*ArchiveStatusResp, error)
+	// 暂停或恢复冷订单归档任务，对所有实例生效
+	SetArchivePaused(ctx context.Context, in *SetArchivePausedReq, opts ...grpc.CallOption) (*ArchiveStatusResp, error)
+	// 软删除已经结束（已完成、已取消、支付超时）的订单，删除后用户查不到该订单
+	DeleteOrder(ctx context.Context, in *DeleteOrderReq, opts ...grpc.CallOption) (*OrderDeletionResp, error)
+	// 恢复已经软删除的订单
+	RestoreOrder(ctx context.Context, in *RestoreOrderReq, opts ...grpc.CallOption) (*OrderDeletionResp, error)
+	// 按条件查询所有用户的订单，分库分表时查询所有分片后合并，按创建时间倒序
+	SearchOrders(ctx context.Context, in *SearchOrdersReq, opts ...grpc.CallOption) (*OrderListResp, error)
+}
+
+type adminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminClient(cc grpc.ClientConnInterface) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) DependencyReport(ctx context.Context, in *DependencyReportReq, opts ...grpc.CallOption) (*DependencyReportResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DependencyReportResp)
+	err := c.cc.Invoke(ctx, Admin_DependencyReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetLogLevel(ctx context.Context, in *GetLogLevelReq, opts ...grpc.CallOption) (*LogLevelResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LogLevelResp)
+	err := c.cc.Invoke(ctx, Admin_GetLogLevel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) SetLogLevel(ctx context.Context, in *SetLogLevelReq, opts ...grpc.CallOption) (*LogLevelResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LogLevelResp)
+	err := c.cc.Invoke(ctx, Admin_SetLogLevel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetArchiveStatus(ctx context.Context, in *GetArchiveStatusReq, opts ...grpc.CallOption) (*ArchiveStatusResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ArchiveStatusResp)
+	err := c.cc.Invoke(ctx, Admin_GetArchiveStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) SetArchivePaused(ctx context.Context, in *SetArchivePausedReq, opts ...grpc.CallOption) (*ArchiveStatusResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ArchiveStatusResp)
+	err := c.cc.Invoke(ctx, Admin_SetArchivePaused_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) DeleteOrder(ctx context.Context, in *DeleteOrderReq, opts ...grpc.CallOption) (*OrderDeletionResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OrderDeletionResp)
+	err := c.cc.Invoke(ctx, Admin_DeleteOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) RestoreOrder(ctx context.Context, in *RestoreOrderReq, opts ...grpc.CallOption) (*OrderDeletionResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OrderDeletionResp)
+	err := c.cc.Invoke(ctx, Admin_RestoreOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) SearchOrders(ctx context.Context, in *SearchOrdersReq, opts ...grpc.CallOption) (*OrderListResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OrderListResp)
+	err := c.cc.Invoke(ctx, Admin_SearchOrders_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServer is the server API for Admin service.
+// All implementations must embed UnimplementedAdminServer
+// for forward compatibility.
+//
+// Admin 运维管理服务
+type AdminServer interface {
+	// 查询依赖健康状态
+	DependencyReport(context.Context, *DependencyReportReq) (*DependencyReportResp, error)
+	// 查询日志级别
+	GetLogLevel(context.Context, *GetLogLevelReq) (*LogLevelResp, error)
+	// 修改日志级别，package 为空时修改全局级别
+	SetLogLevel(context.Context, *SetLogLevelReq) (*LogLevelResp, error)
+	// 查询冷订单归档任务的状态
+	GetArchiveStatus(context.Context, *GetArchiveStatusReq) (*ArchiveStatusResp, error)
+	// 暂停或恢复冷订单归档任务，对所有实例生效
+	SetArchivePaused(context.Context, *SetArchivePausedReq) (*ArchiveStatusResp, error)
+	// 软删除已经结束（已完成、已取消、支付超时）的订单，删除后用户查不到该订单
+	DeleteOrder(context.Context, *DeleteOrderReq) (*OrderDeletionResp, error)
+	// 恢复已经软删除的订单
+	RestoreOrder(context.Context, *RestoreOrderReq) (*OrderDeletionResp, error)
+	// 按条件查询所有用户的订单，分库分表时查询所有分片后合并，按创建时间倒序
+	SearchOrders(context.Context, *SearchOrdersReq) (*OrderListResp, error)
+	mustEmbedUnimplementedAdminServer()
+}
+
+// UnimplementedAdminServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAdminServer struct{}
+
+func (UnimplementedAdminServer) DependencyReport(context.Context, *DependencyReportReq) (*DependencyReportResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DependencyReport not implemented")
+}
+func (UnimplementedAdminServer) GetLogLevel(context.Context, *GetLogLevelReq) (*LogLevelResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLogLevel not implemented")
+}
+func (UnimplementedAdminServer) SetLogLevel(context.Context, *SetLogLevelReq) (*LogLevelResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLogLevel not implemented")
+}
+func (UnimplementedAdminServer) GetArchiveStatus(context.Context, *GetArchiveStatusReq) (*ArchiveStatusResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetArchiveStatus not implemented")
+}
+func (UnimplementedAdminServer) SetArchivePaused(context.Context, *SetArchivePausedReq) (*ArchiveStatusResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetArchivePaused not implemented")
+}
+func (UnimplementedAdminServer) DeleteOrder(context.Context, *DeleteOrderReq) (*OrderDeletionResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteOrder not implemented")
+}
+func (UnimplementedAdminServer) RestoreOrder(context.Context, *RestoreOrderReq) (*OrderDeletionResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreOrder not implemented")
+}
+func (UnimplementedAdminServer) SearchOrders(context.Context, *SearchOrdersReq) (*OrderListResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchOrders not implemented")
+}
+func (UnimplementedAdminServer) mustEmbedUnimplementedAdminServer() {}
+func (UnimplementedAdminServer) testEmbeddedByValue()               {}
+
+// UnsafeAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServer will
+// result in compilation errors.
+type UnsafeAdminServer interface {
+	mustEmbedUnimplementedAdminServer()
+}
+
+func RegisterAdminServer(s grpc.ServiceRegistrar, srv AdminServer) {
+	// If the following call pancis, it indicates UnimplementedAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Admin_ServiceDesc, srv)
+}
+
+func _Admin_DependencyReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DependencyReportReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).DependencyReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_DependencyReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).DependencyReport(ctx, req.(*DependencyReportReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLogLevelReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetLogLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_GetLogLevel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetLogLevel(ctx, req.(*GetLogLevelReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_SetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLogLevelReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).SetLogLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_SetLogLevel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).SetLogLevel(ctx, req.(*SetLogLevelReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetArchiveStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetArchiveStatusReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetArchiveStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_GetArchiveStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetArchiveStatus(ctx, req.(*GetArchiveStatusReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_SetArchivePaused_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetArchivePausedReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).SetArchivePaused(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_SetArchivePaused_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).SetArchivePaused(ctx, req.(*SetArchivePausedReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_DeleteOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteOrderReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).DeleteOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_DeleteOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).DeleteOrder(ctx, req.(*DeleteOrderReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_RestoreOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreOrderReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).RestoreOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_RestoreOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).RestoreOrder(ctx, req.(*RestoreOrderReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_SearchOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchOrdersReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).SearchOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_SearchOrders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).SearchOrders(ctx, req.(*SearchOrdersReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Admin_ServiceDesc is the grpc.ServiceDesc for Admin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Admin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "DependencyReport",
+			Handler:    _Admin_DependencyReport_Handler,
+		},
+		{
+			MethodName: "GetLogLevel",
+			Handler:    _Admin_GetLogLevel_Handler,
+		},
+		{
+			MethodName: "SetLogLevel",
+			Handler:    _Admin_SetLogLevel_Handler,
+		},
+		{
+			MethodName: "GetArchiveStatus",
+			Handler:    _Admin_GetArchiveStatus_Handler,
+		},
+		{
+			MethodName: "SetArchivePaused",
+			Handler:    _Admin_SetArchivePaused_Handler,
+		},
+		{
+			MethodName: "DeleteOrder",
+			Handler:    _Admin_DeleteOrder_Handler,
+		},
+		{
+			MethodName: "RestoreOrder",
+			Handler:    _Admin_RestoreOrder_Handler,
+		},
+		{
+			MethodName: "SearchOrders",
+			Handler:    _Admin_SearchOrders_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "admin.proto",
+}
@@ -0,0 +1,8 @@
+package proto
+
+import _ "embed"
+
+// OrderOpenAPI 由 protoc-gen-openapiv2 根据 order.proto 生成的 OpenAPI 文档
+//
+//go:embed order.swagger.json
+var OrderOpenAPI []byte
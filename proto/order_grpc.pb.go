@@ -20,9 +20,11 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	Order_CreateOrder_FullMethodName       = "/proto.Order/CreateOrder"
+	Order_QuoteOrder_FullMethodName        = "/proto.Order/QuoteOrder"
 	Order_OrderList_FullMethodName         = "/proto.Order/OrderList"
 	Order_OrderDetail_FullMethodName       = "/proto.Order/OrderDetail"
 	Order_UpdateOrderStatus_FullMethodName = "/proto.Order/UpdateOrderStatus"
+	Order_GetOrderHistory_FullMethodName   = "/proto.Order/GetOrderHistory"
 )
 
 // OrderClient is the client API for Order service.
@@ -33,12 +35,16 @@ const (
 type OrderClient interface {
 	// 创建订单
 	CreateOrder(ctx context.Context, in *CreateOrderReq, opts ...grpc.CallOption) (*Response, error)
+	// 订单试算：创建订单前计算价格明细
+	QuoteOrder(ctx context.Context, in *QuoteOrderReq, opts ...grpc.CallOption) (*OrderQuote, error)
 	// 获取订单列表
 	OrderList(ctx context.Context, in *OrderListReq, opts ...grpc.CallOption) (*OrderListResp, error)
 	// 查询订单详情
 	OrderDetail(ctx context.Context, in *OrderDetailReq, opts ...grpc.CallOption) (*OrderDetailInfo, error)
 	// 更新订单状态
 	UpdateOrderStatus(ctx context.Context, in *OrderStatus, opts ...grpc.CallOption) (*Response, error)
+	// 查询订单的状态变化历史
+	GetOrderHistory(ctx context.Context, in *OrderHistoryReq, opts ...grpc.CallOption) (*OrderHistoryResp, error)
 }
 
 type orderClient struct {
@@ -59,6 +65,16 @@ func (c *orderClient) CreateOrder(ctx context.Context, in *CreateOrderReq, opts
 	return out, nil
 }
 
+func (c *orderClient) QuoteOrder(ctx context.Context, in *QuoteOrderReq, opts ...grpc.CallOption) (*OrderQuote, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OrderQuote)
+	err := c.cc.Invoke(ctx, Order_QuoteOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *orderClient) OrderList(ctx context.Context, in *OrderListReq, opts ...grpc.CallOption) (*OrderListResp, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(OrderListResp)
@@ -89,6 +105,16 @@ func (c *orderClient) UpdateOrderStatus(ctx context.Context, in *OrderStatus, op
 	return out, nil
 }
 
+func (c *orderClient) GetOrderHistory(ctx context.Context, in *OrderHistoryReq, opts ...grpc.CallOption) (*OrderHistoryResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OrderHistoryResp)
+	err := c.cc.Invoke(ctx, Order_GetOrderHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // OrderServer is the server API for Order service.
 // All implementations must embed UnimplementedOrderServer
 // for forward compatibility.
@@ -97,12 +123,16 @@ func (c *orderClient) UpdateOrderStatus(ctx context.Context, in *OrderStatus, op
 type OrderServer interface {
 	// 创建订单
 	CreateOrder(context.Context, *CreateOrderReq) (*Response, error)
+	// 订单试算：创建订单前计算价格明细
+	QuoteOrder(context.Context, *QuoteOrderReq) (*OrderQuote, error)
 	// 获取订单列表
 	OrderList(context.Context, *OrderListReq) (*OrderListResp, error)
 	// 查询订单详情
 	OrderDetail(context.Context, *OrderDetailReq) (*OrderDetailInfo, error)
 	// 更新订单状态
 	UpdateOrderStatus(context.Context, *OrderStatus) (*Response, error)
+	// 查询订单的状态变化历史
+	GetOrderHistory(context.Context, *OrderHistoryReq) (*OrderHistoryResp, error)
 	mustEmbedUnimplementedOrderServer()
 }
 
@@ -116,6 +146,9 @@ type UnimplementedOrderServer struct{}
 func (UnimplementedOrderServer) CreateOrder(context.Context, *CreateOrderReq) (*Response, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateOrder not implemented")
 }
+func (UnimplementedOrderServer) QuoteOrder(context.Context, *QuoteOrderReq) (*OrderQuote, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QuoteOrder not implemented")
+}
 func (UnimplementedOrderServer) OrderList(context.Context, *OrderListReq) (*OrderListResp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method OrderList not implemented")
 }
@@ -125,6 +158,9 @@ func (UnimplementedOrderServer) OrderDetail(context.Context, *OrderDetailReq) (*
 func (UnimplementedOrderServer) UpdateOrderStatus(context.Context, *OrderStatus) (*Response, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateOrderStatus not implemented")
 }
+func (UnimplementedOrderServer) GetOrderHistory(context.Context, *OrderHistoryReq) (*OrderHistoryResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrderHistory not implemented")
+}
 func (UnimplementedOrderServer) mustEmbedUnimplementedOrderServer() {}
 func (UnimplementedOrderServer) testEmbeddedByValue()               {}
 
@@ -164,6 +200,24 @@ func _Order_CreateOrder_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Order_QuoteOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QuoteOrderReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServer).QuoteOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Order_QuoteOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServer).QuoteOrder(ctx, req.(*QuoteOrderReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Order_OrderList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(OrderListReq)
 	if err := dec(in); err != nil {
@@ -218,6 +272,24 @@ func _Order_UpdateOrderStatus_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Order_GetOrderHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OrderHistoryReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServer).GetOrderHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Order_GetOrderHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServer).GetOrderHistory(ctx, req.(*OrderHistoryReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Order_ServiceDesc is the grpc.ServiceDesc for Order service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -229,6 +301,10 @@ var Order_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CreateOrder",
 			Handler:    _Order_CreateOrder_Handler,
 		},
+		{
+			MethodName: "QuoteOrder",
+			Handler:    _Order_QuoteOrder_Handler,
+		},
 		{
 			MethodName: "OrderList",
 			Handler:    _Order_OrderList_Handler,
@@ -241,6 +317,10 @@ var Order_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateOrderStatus",
 			Handler:    _Order_UpdateOrderStatus_Handler,
 		},
+		{
+			MethodName: "GetOrderHistory",
+			Handler:    _Order_GetOrderHistory_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "order.proto",
@@ -25,6 +25,7 @@ const (
 	Stock_RollbackStock_FullMethodName    = "/proto.Stock/RollbackStock"
 	Stock_BatchGetStock_FullMethodName    = "/proto.Stock/BatchGetStock"
 	Stock_BatchReduceStock_FullMethodName = "/proto.Stock/BatchReduceStock"
+	Stock_ListDeductions_FullMethodName   = "/proto.Stock/ListDeductions"
 )
 
 // StockClient is the client API for Stock service.
@@ -37,6 +38,7 @@ type StockClient interface {
 	RollbackStock(ctx context.Context, in *ReduceStockInfo, opts ...grpc.CallOption) (*Response, error)
 	BatchGetStock(ctx context.Context, in *StockInfoList, opts ...grpc.CallOption) (*StockInfoList, error)
 	BatchReduceStock(ctx context.Context, in *StockInfoList, opts ...grpc.CallOption) (*Response, error)
+	ListDeductions(ctx context.Context, in *ListDeductionsReq, opts ...grpc.CallOption) (*ListDeductionsResp, error)
 }
 
 type stockClient struct {
@@ -107,6 +109,16 @@ func (c *stockClient) BatchReduceStock(ctx context.Context, in *StockInfoList, o
 	return out, nil
 }
 
+func (c *stockClient) ListDeductions(ctx context.Context, in *ListDeductionsReq, opts ...grpc.CallOption) (*ListDeductionsResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDeductionsResp)
+	err := c.cc.Invoke(ctx, Stock_ListDeductions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // StockServer is the server API for Stock service.
 // All implementations must embed UnimplementedStockServer
 // for forward compatibility.
@@ -117,6 +129,7 @@ type StockServer interface {
 	RollbackStock(context.Context, *ReduceStockInfo) (*Response, error)
 	BatchGetStock(context.Context, *StockInfoList) (*StockInfoList, error)
 	BatchReduceStock(context.Context, *StockInfoList) (*Response, error)
+	ListDeductions(context.Context, *ListDeductionsReq) (*ListDeductionsResp, error)
 	mustEmbedUnimplementedStockServer()
 }
 
@@ -145,6 +158,9 @@ func (UnimplementedStockServer) BatchGetStock(context.Context, *StockInfoList) (
 func (UnimplementedStockServer) BatchReduceStock(context.Context, *StockInfoList) (*Response, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method BatchReduceStock not implemented")
 }
+func (UnimplementedStockServer) ListDeductions(context.Context, *ListDeductionsReq) (*ListDeductionsResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDeductions not implemented")
+}
 func (UnimplementedStockServer) mustEmbedUnimplementedStockServer() {}
 func (UnimplementedStockServer) testEmbeddedByValue()               {}
 
@@ -274,6 +290,24 @@ func _Stock_BatchReduceStock_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Stock_ListDeductions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDeductionsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StockServer).ListDeductions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Stock_ListDeductions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StockServer).ListDeductions(ctx, req.(*ListDeductionsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Stock_ServiceDesc is the grpc.ServiceDesc for Stock service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -305,6 +339,10 @@ var Stock_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "BatchReduceStock",
 			Handler:    _Stock_BatchReduceStock_Handler,
 		},
+		{
+			MethodName: "ListDeductions",
+			Handler:    _Stock_ListDeductions_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "stock.proto",
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"order_service/auth"
+	"order_service/logger"
+	"order_service/metrics"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// RetryAfterKey 被限流时返回的 header，建议等待的秒数
+const RetryAfterKey = "retry-after"
+
+// userIDGetter proto 生成的请求结构体有 user_id 字段时实现该接口
+type userIDGetter interface {
+	GetUserId() int64
+}
+
+// goodsIDGetter proto 生成的请求结构体有 goods_id 字段时实现该接口
+type goodsIDGetter interface {
+	GetGoodsId() int64
+}
+
+// UnaryServerInterceptor 按 rate_limit 配置限流，放在鉴权之后，用户维度按鉴权识别出的用户计数
+// 超过限制时返回 ResourceExhausted，错误详情中带 RetryInfo，header 中带 retry-after
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		r := lookup(info.FullMethod)
+		if r == nil {
+			return handler(ctx, req)
+		}
+		var goodsId int64
+		if g, ok := req.(goodsIDGetter); ok {
+			goodsId = g.GetGoodsId()
+		}
+		limiter, wait := r.allow(ctx, userID(ctx, req), goodsId)
+		if wait <= 0 {
+			return handler(ctx, req)
+		}
+
+		metrics.RateLimited.WithLabelValues(info.FullMethod, limiter).Inc()
+		logger.Ctx(ctx).Info("request rate limited",
+			zap.String("method", info.FullMethod),
+			zap.String("limiter", limiter),
+			zap.Duration("retry_after", wait))
+		return nil, exhausted(ctx, wait)
+	}
+}
+
+// userID 按用户限流时使用的用户ID，经过 JWT 校验的运维人员不按用户限流
+// 没有开启鉴权时 x-operator 等调用方信息未经校验，仍然按请求中的 user_id 限流
+func userID(ctx context.Context, req interface{}) int64 {
+	if auth.IsAdmin(ctx) {
+		return 0
+	}
+	if c, ok := auth.FromContext(ctx); ok && c.UserID > 0 {
+		return c.UserID
+	}
+	if r, ok := req.(userIDGetter); ok {
+		return r.GetUserId()
+	}
+	return 0
+}
+
+// exhausted 限流错误，重试等待时间向上取整到秒
+func exhausted(ctx context.Context, wait time.Duration) error {
+	seconds := int64(math.Ceil(wait.Seconds()))
+	grpc.SetHeader(ctx, metadata.Pairs(RetryAfterKey, strconv.FormatInt(seconds, 10)))
+
+	st := status.New(codes.ResourceExhausted, "请求过于频繁，请稍后再试")
+	if ds, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)}); err == nil {
+		st = ds
+	}
+	return st.Err()
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"order_service/auth"
+	"order_service/config"
+	"order_service/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// stubWindow 用内存计数代替 Redis 滑动窗口，窗口内不过期
+func stubWindow(t *testing.T) map[string]int {
+	t.Helper()
+	counts := make(map[string]int)
+	prev := windowCheck
+	windowCheck = func(_ context.Context, scope string, limit int, window time.Duration) (time.Duration, error) {
+		if counts[scope] >= limit {
+			return window, nil
+		}
+		counts[scope]++
+		return 0, nil
+	}
+	t.Cleanup(func() { windowCheck = prev })
+	return counts
+}
+
+func setRules(t *testing.T, rules ...config.RateLimitRule) {
+	t.Helper()
+	Reload(&config.RateLimitConfig{Enable: true, Rules: rules})
+	t.Cleanup(func() { Reload(nil) })
+}
+
+// call 按服务端的拦截器顺序（先鉴权再限流）调用 CreateOrder
+func call(ctx context.Context, req *proto.CreateOrderReq) error {
+	info := &grpc.UnaryServerInfo{FullMethod: "/order.Order/CreateOrder"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }
+	limit := UnaryServerInterceptor()
+	_, err := auth.UnaryServerInterceptor()(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return limit(ctx, req, info, handler)
+	})
+	return err
+}
+
+// 没有开启鉴权时 x-operator 未经校验，带 x-operator 的请求仍然按 user_id 限流
+func TestOperatorHeaderDoesNotBypassUserLimit(t *testing.T) {
+	counts := stubWindow(t)
+	setRules(t, config.RateLimitRule{Method: "CreateOrder", UserLimit: 2, UserWindow: time.Minute})
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(auth.OperatorKey, "x"))
+	req := &proto.CreateOrderReq{UserId: 7, GoodsId: 1, Num: 1}
+	for i := 0; i < 2; i++ {
+		if err := call(ctx, req); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	err := call(ctx, req)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("third request err = %v, want ResourceExhausted", err)
+	}
+	if counts["CreateOrder:user:7"] != 2 {
+		t.Errorf("user window counts = %v", counts)
+	}
+}
+
+// 被用户维度拒绝的请求不消耗令牌桶，其他用户不受影响
+func TestUserRejectionKeepsGlobalToken(t *testing.T) {
+	stubWindow(t)
+	setRules(t, config.RateLimitRule{Method: "/order.Order/CreateOrder", Rate: 0.001, Burst: 2, UserLimit: 1, UserWindow: time.Minute})
+
+	ctx := context.Background()
+	if err := call(ctx, &proto.CreateOrderReq{UserId: 1}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := call(ctx, &proto.CreateOrderReq{UserId: 1}); status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("user 1 retry %d err = %v, want ResourceExhausted", i, err)
+		}
+	}
+	if err := call(ctx, &proto.CreateOrderReq{UserId: 2}); err != nil {
+		t.Fatalf("user 2 should get the remaining token: %v", err)
+	}
+	if err := call(ctx, &proto.CreateOrderReq{UserId: 3}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("user 3 err = %v, want ResourceExhausted after the bucket is empty", err)
+	}
+}
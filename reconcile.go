@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"order_service/biz/order"
+	"order_service/certs"
+	"order_service/config"
+	"order_service/dao/store"
+	"order_service/logger"
+	"order_service/rpc"
+)
+
+// runReconcile reconcile 子命令：手动执行一次订单-库存对账，报告以 JSON 格式输出到标准输出
+// 未指定的参数取配置文件中 stock_reconcile 的配置
+func runReconcile(cfn string, args []string) int {
+	fs := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	window := fs.Duration("window", 0, "对账最近这段时间内的扣减记录，默认取 stock_reconcile.window")
+	orphanAge := fs.Duration("orphan-age", 0, "扣减后超过这个时间仍然没有订单的视为孤儿扣减，默认取 stock_reconcile.orphan_age")
+	dryRun := fs.Bool("dry-run", false, "只生成报告，不回滚孤儿扣减")
+	fs.Parse(args)
+
+	if err := config.Init(cfn); err != nil {
+		fmt.Fprintf(os.Stderr, "load config failed, err:%v\n", err)
+		return 1
+	}
+	if err := logger.Init(config.Conf.LogConfig, config.Conf.Mode); err != nil {
+		fmt.Fprintf(os.Stderr, "init logger failed, err:%v\n", err)
+		return 1
+	}
+	if err := store.Init(config.Conf); err != nil {
+		fmt.Fprintf(os.Stderr, "init storage failed, err:%v\n", err)
+		return 1
+	}
+	defer store.Close()
+	if err := certs.Init(config.Conf.TLSConfig, config.Conf.GoodsService.TLS || config.Conf.StockService.TLS); err != nil {
+		fmt.Fprintf(os.Stderr, "load tls certificates failed, err:%v\n", err)
+		return 1
+	}
+	if err := rpc.InitSrvClient(); err != nil {
+		fmt.Fprintf(os.Stderr, "init stock client failed, err:%v\n", err)
+		return 1
+	}
+
+	opts := order.DefaultReconcileOptions()
+	if *window > 0 {
+		opts.Start = opts.End.Add(-*window)
+	}
+	if *orphanAge > 0 {
+		opts.OrphanAge = *orphanAge
+	}
+	if *dryRun {
+		opts.DryRun = true
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	report, err := order.ReconcileStock(ctx, opts)
+	// 对账中途失败时也输出已经处理的部分
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reconcile failed, err:%v\n", err)
+		return 1
+	}
+	for _, o := range report.Orphans {
+		if o.Action == order.OrphanActionFailed {
+			return 2
+		}
+	}
+	return 0
+}
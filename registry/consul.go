@@ -5,10 +5,19 @@ import (
 	"net"
 
 	"github.com/hashicorp/consul/api"
+	"go.uber.org/zap"
 )
 
 type consul struct {
-	client *api.Client
+	client   *api.Client
+	checkTLS *HealthCheckTLS
+}
+
+// HealthCheckTLS gRPC 服务开启 TLS 时 Consul 健康检查的 TLS 配置
+// Consul agent 使用自己配置的 CA 校验服务端证书，健康检查不需要客户端证书
+type HealthCheckTLS struct {
+	ServerName string // 校验服务端证书时使用的名称
+	SkipVerify bool   // 不校验服务端证书
 }
 
 var Reg Register
@@ -17,14 +26,15 @@ var Reg Register
 var _ Register = (*consul)(nil)
 
 // Init 连接至consul服务，初始化全局的consul对象
-func Init(addr string) (err error) {
+// checkTLS 不为 nil 时健康检查通过 TLS 连接服务
+func Init(addr string, checkTLS *HealthCheckTLS) (err error) {
 	cfg := api.DefaultConfig()
 	cfg.Address = addr
 	c, err := api.NewClient(cfg)
 	if err != nil {
 		return err
 	}
-	Reg = &consul{c}
+	Reg = &consul{client: c, checkTLS: checkTLS}
 	return
 }
 
@@ -42,7 +52,7 @@ func getOutboundIP() (net.IP, error) {
 // RegisterService 将gRPC服务注册到consul
 func (c *consul) RegisterService(serviceName string, ip string, port int, tags []string) error {
 	outIp, _ := getOutboundIP()
-	fmt.Println(outIp.String())
+	zap.L().Debug("outbound ip", zap.String("ip", outIp.String()))
 	// 健康检查
 	check := &api.AgentServiceCheck{
 		GRPC:                           fmt.Sprintf("%s:%d", outIp, port), // 这里一定是外部可以访问的地址
@@ -50,6 +60,11 @@ func (c *consul) RegisterService(serviceName string, ip string, port int, tags [
 		Interval:                       "5s",
 		DeregisterCriticalServiceAfter: "10s",
 	}
+	if c.checkTLS != nil {
+		check.GRPCUseTLS = true
+		check.TLSServerName = c.checkTLS.ServerName
+		check.TLSSkipVerify = c.checkTLS.SkipVerify
+	}
 	srv := &api.AgentServiceRegistration{
 		ID:      fmt.Sprintf("%s-%s-%d", serviceName, ip, port), // 服务唯一ID
 		Name:    serviceName,                                    // 服务名称
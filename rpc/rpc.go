@@ -1,21 +1,29 @@
 package rpc
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
 
+	"order_service/certs"
 	"order_service/config"
+	"order_service/metrics"
 	"order_service/proto"
 
 	_ "github.com/mbobakov/grpc-consul-resolver"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/connectivity"
 )
 
 var (
 	GoodsCli proto.GoodsClient
 	StockCli proto.StockClient
+
+	goodsConn *grpc.ClientConn
+	stockConn *grpc.ClientConn
 )
 
 func InitSrvClient() error {
@@ -31,34 +39,85 @@ func InitSrvClient() error {
 	}
 
 	// 初始化商品服务客户端
-	goodsConn, err := grpc.Dial(
+	var err error
+	goodsConn, err = grpc.Dial(
 		fmt.Sprintf("consul://%s/%s?wait=14s", config.Conf.ConsulConfig.Addr, config.Conf.GoodsService.Name),
 		grpc.WithDefaultServiceConfig(`{"loadBalancingPolicy": "round_robin"}`),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(certs.ClientCredentials(config.Conf.GoodsService.TLS,
+			serverName(config.Conf.GoodsService.Name, config.Conf.GoodsService.ServerName))),
+		grpc.WithChainUnaryInterceptor(metrics.UnaryClientInterceptor()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
 		grpc.WithBlock(),                // 等待连接建立
 		grpc.WithTimeout(5*time.Second), // 设置超时时间
 	)
 	if err != nil {
-		fmt.Printf("Failed to dial goods_srv: ConsulAddr=%s, ServiceName=%s, err=%v\n",
-			config.Conf.ConsulConfig.Addr, config.Conf.GoodsService.Name, err)
+		zap.L().Error("Failed to dial goods_srv",
+			zap.String("consul_addr", config.Conf.ConsulConfig.Addr),
+			zap.String("service_name", config.Conf.GoodsService.Name),
+			zap.Error(err))
 		return err
 	}
 	GoodsCli = proto.NewGoodsClient(goodsConn)
 
 	// 初始化库存服务客户端
-	stockConn, err := grpc.Dial(
+	stockConn, err = grpc.Dial(
 		fmt.Sprintf("consul://%s/%s?wait=14s", config.Conf.ConsulConfig.Addr, config.Conf.StockService.Name),
 		grpc.WithDefaultServiceConfig(`{"loadBalancingPolicy": "round_robin"}`),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(certs.ClientCredentials(config.Conf.StockService.TLS,
+			serverName(config.Conf.StockService.Name, config.Conf.StockService.ServerName))),
+		grpc.WithChainUnaryInterceptor(metrics.UnaryClientInterceptor()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
 		grpc.WithBlock(),
 		grpc.WithTimeout(5*time.Second),
 	)
 	if err != nil {
-		fmt.Printf("Failed to dial stock_srv: ConsulAddr=%s, ServiceName=%s, err=%v\n",
-			config.Conf.ConsulConfig.Addr, config.Conf.StockService.Name, err)
+		zap.L().Error("Failed to dial stock_srv",
+			zap.String("consul_addr", config.Conf.ConsulConfig.Addr),
+			zap.String("service_name", config.Conf.StockService.Name),
+			zap.Error(err))
 		return err
 	}
 	StockCli = proto.NewStockClient(stockConn)
 
 	return nil
 }
+
+// serverName 校验下游服务证书时使用的名称，没有配置时使用服务名
+func serverName(name, override string) string {
+	if len(override) > 0 {
+		return override
+	}
+	return name
+}
+
+// PingGoods 检查商品服务连接状态（供健康检查使用）
+func PingGoods(ctx context.Context) error {
+	return checkConn(ctx, goodsConn, config.Conf.GoodsService.Name)
+}
+
+// PingStock 检查库存服务连接状态（供健康检查使用）
+func PingStock(ctx context.Context) error {
+	return checkConn(ctx, stockConn, config.Conf.StockService.Name)
+}
+
+// checkConn 根据连接状态判断下游服务是否可用
+// 连接处于 Idle 时主动触发一次连接，并在 ctx 超时前等待状态变化
+func checkConn(ctx context.Context, conn *grpc.ClientConn, name string) error {
+	if conn == nil {
+		return fmt.Errorf("%s client not initialized", name)
+	}
+	for {
+		state := conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.Shutdown:
+			return fmt.Errorf("%s connection is shutdown", name)
+		case connectivity.Idle:
+			conn.Connect()
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("%s connection state: %s", name, state)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package snowflake
 
 import (
 	"errors"
+	"strconv"
 	"time"
 
 	sf "github.com/bwmarrin/snowflake" // 导入 snowflake 库
@@ -11,12 +12,16 @@ const (
 	_dafaultStartTime = "2020-12-31" // 默认的起始时间，用于计算时间戳偏移
 )
 
-var node *sf.Node // 全局的 Snowflake 节点实例
+var (
+	node     *sf.Node // 全局的 Snowflake 节点实例
+	geneBits uint8    // ID 低位保存基因（分片号）的位数
+)
 
 // Init 初始化 Snowflake 算法组件
 // startTime: 自定义的起始时间（格式为 "2006-01-02"）
 // machineID: 机器 ID，用于区分不同实例
-func Init(startTime string, machineID int64) (err error) {
+// genes: ID 低位保存基因的位数，占用序列号的低位，时间戳和机器 ID 的位置不变
+func Init(startTime string, machineID int64, genes uint8) (err error) {
 	// 检查机器 ID 是否有效
 	if machineID < 0 {
 		return errors.New("snowflake need machineID")
@@ -34,18 +39,31 @@ func Init(startTime string, machineID int64) (err error) {
 		return // 如果解析失败，返回错误
 	}
 
+	if genes >= sf.StepBits {
+		return errors.New("snowflake gene bits too large")
+	}
+
 	// 设置 Snowflake 的起始时间戳偏移
 	sf.Epoch = st.UnixNano() / 1000000 // 将起始时间转换为毫秒级时间戳
+	sf.StepBits -= genes
+	geneBits = genes
 	node, err = sf.NewNode(machineID) // 创建 Snowflake 节点实例
 	return
 }
 
 // GenID 生成一个 Snowflake ID（int64 类型）
 func GenID() int64 {
-	return node.Generate().Int64() // 调用 Snowflake 节点的 Generate 方法生成 ID
+	return GenIDWithGene(0)
+}
+
+// GenIDWithGene 生成一个低位保存基因的 Snowflake ID，gene 只保留低 genes 位
+// 例如按用户ID分库时用用户ID作为基因，订单号和用户ID落在同一个分片
+func GenIDWithGene(gene int64) int64 {
+	mask := int64(1)<<geneBits - 1
+	return node.Generate().Int64()<<geneBits | gene&mask // 调用 Snowflake 节点的 Generate 方法生成 ID
 }
 
 // GenIDStr 生成一个 Snowflake ID（字符串类型）
 func GenIDStr() string {
-	return node.Generate().String() // 调用 Snowflake 节点的 Generate 方法生成 ID，并转换为字符串
+	return strconv.FormatInt(GenID(), 10) // 生成 ID，并转换为字符串
 }         
\ No newline at end of file
@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RocketMQ 消息的 trace 上下文透传
+// trace 上下文（traceparent 等）写入消息属性中，消费者从消息属性中恢复。
+
+// messageCarrier 将 RocketMQ 消息属性适配为 propagation.TextMapCarrier
+type messageCarrier struct {
+	msg *primitive.Message
+}
+
+var _ propagation.TextMapCarrier = messageCarrier{}
+
+func (c messageCarrier) Get(key string) string {
+	return c.msg.GetProperty(key)
+}
+
+func (c messageCarrier) Set(key, value string) {
+	c.msg.WithProperty(key, value)
+}
+
+func (c messageCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.msg.GetProperties()))
+	for k := range c.msg.GetProperties() {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// InjectMessage 把 ctx 中的 trace 上下文写入消息属性
+func InjectMessage(ctx context.Context, msg *primitive.Message) {
+	otel.GetTextMapPropagator().Inject(ctx, messageCarrier{msg})
+}
+
+// ExtractMessage 从消息属性中恢复 trace 上下文
+func ExtractMessage(ctx context.Context, msg *primitive.Message) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, messageCarrier{msg})
+}
+
+// StartConsumerSpan 从消息中恢复 trace 上下文并开启一个消费 span
+func StartConsumerSpan(ctx context.Context, msg *primitive.MessageExt) (context.Context, trace.Span) {
+	ctx = ExtractMessage(ctx, &msg.Message)
+	return Tracer().Start(ctx, "consume "+msg.Topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("rocketmq"),
+			semconv.MessagingDestinationName(msg.Topic),
+			semconv.MessagingMessageID(msg.MsgId),
+			attribute.Int("messaging.rocketmq.reconsume_times", int(msg.ReconsumeTimes)),
+		),
+	)
+}